@@ -9,6 +9,7 @@ require (
 	github.com/go-git/go-git/v5 v5.9.0
 	github.com/joho/godotenv v1.5.1
 	github.com/olekukonko/tablewriter v0.0.5
+	github.com/smacker/go-tree-sitter v0.0.0-20240827094217-dd81d9e9be82
 	github.com/wilcosheh/tfidf v0.0.0-20170517095906-2847b6a5524e
 	golang.org/x/exp v0.0.0-20230905200255-921286631fa9
 )
@@ -0,0 +1,90 @@
+package arg_parser
+
+import (
+	"flag"
+	"fmt"
+	"hercules/src/clustering"
+	"hercules/src/util"
+	"hercules/src/workflow"
+	"io"
+	"os"
+	"strings"
+)
+
+// clusterCommand is `hercules cluster`, which groups the submissions of a cohort, and the external repos
+// they copied from, into clusters of shared code
+func clusterCommand(args []string) {
+	var method string
+	var threshold float64
+	var externalDirs stringSliceFlag
+	var dotPath string
+	var graphMLPath string
+	var jsonPath string
+	options := workflow.DefaultOptions()
+
+	flagSet := flag.NewFlagSet("cluster", flag.ExitOnError)
+	flagSet.StringVar(&method, "method", clustering.METHOD_COMPONENTS, "How to cluster, from "+strings.Join(clustering.METHODS, ", ")+".")
+	flagSet.Float64Var(&threshold, "threshold", workflow.COMBINED_SIMILARITY_THRESHOLD, "The combined similarity above which two repos are clustered.")
+	flagSet.Var(&externalDirs, "external", "A directory of an external repo, e.g. a known solution, to compare the submissions with. Can be repeated.")
+	flagSet.StringVar(&options.CorpusIndexPath, "corpus-index", "", "A corpus index built with hercules index, whose repos are external repos too.")
+	flagSet.StringVar(&dotPath, "dot", "", "Where to save the graph as Graphviz DOT.")
+	flagSet.StringVar(&graphMLPath, "graphml", "", "Where to save the graph as GraphML.")
+	flagSet.StringVar(&jsonPath, "json", "", "Where to save the graph and the clusters as JSON.")
+	comparisonFlags := registerComparisonFlags(flagSet, &options,
+		"base", "combine", "max-matches-per-file", "lsh", "lsh-bands", "lsh-rows", "weight-by",
+	)
+	flagSet.Parse(args)
+	submissionDirs := flagSet.Args()
+
+	if len(submissionDirs) == 0 || len(submissionDirs)+len(externalDirs) < 2 && options.CorpusIndexPath == "" {
+		fmt.Println("Usage: hercules cluster [--method=<METHOD>] [--threshold=<SIMILARITY>] [--external=<DIR>]... [--corpus-index=<INDEX_PATH>] [--dot=<PATH>] [--graphml=<PATH>] [--json=<PATH>] <SUBMISSION_DIR>...")
+		os.Exit(1)
+	}
+	for _, dir := range append(append([]string{}, submissionDirs...), externalDirs...) {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Printf("%s is not a directory.\n", dir)
+			os.Exit(1)
+		}
+	}
+
+	if !util.Contains(clustering.METHODS, method) {
+		fmt.Printf("Invalid --method: expected one of %s\n", strings.Join(clustering.METHODS, ", "))
+		os.Exit(1)
+	}
+	if threshold < 0 || threshold >= 1 {
+		fmt.Println("Invalid --threshold: must be at least 0 and below 1")
+		os.Exit(1)
+	}
+	comparisonFlags.parse(&options)
+
+	graph, err := workflow.BuildCohortGraph(submissionDirs, externalDirs, options)
+	if err != nil {
+		fmt.Printf("Error comparing the repos: %v\n", err)
+		os.Exit(1)
+	}
+	clusters, err := graph.Cluster(method, threshold)
+	if err != nil {
+		fmt.Printf("Error clustering the repos: %v\n", err)
+		os.Exit(1)
+	}
+	workflow.RenderClustersTable(graph, clusters)
+
+	exports := []struct {
+		path  string
+		write func(writer io.Writer) error
+	}{
+		{dotPath, func(writer io.Writer) error { return clustering.WriteDOT(writer, graph, clusters, threshold) }},
+		{graphMLPath, func(writer io.Writer) error { return clustering.WriteGraphML(writer, graph, clusters) }},
+		{jsonPath, func(writer io.Writer) error { return clustering.WriteJSON(writer, graph, clusters, method, threshold) }},
+	}
+	for _, export := range exports {
+		if export.path == "" {
+			continue
+		}
+		if err := clustering.SaveFile(export.path, export.write); err != nil {
+			fmt.Printf("Error saving the graph: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Saved the graph to %s\n", export.path)
+	}
+}
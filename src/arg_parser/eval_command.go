@@ -0,0 +1,51 @@
+package arg_parser
+
+import (
+	"flag"
+	"fmt"
+	"hercules/src/workflow"
+	"os"
+)
+
+// evalCommand is `hercules eval`, which measures how well the metrics find the copies of a labeled dataset
+func evalCommand(args []string) {
+	var pairsPath string
+	var jsonPath string
+	var markdownPath string
+	options := workflow.DefaultOptions()
+
+	flagSet := flag.NewFlagSet("eval", flag.ExitOnError)
+	flagSet.StringVar(&pairsPath, "pairs", "", "A CSV file of labeled pairs of files or of repo directories, with the header file1,file2,label[,obfuscation].")
+	flagSet.StringVar(&jsonPath, "json", "", "Where to save the report as JSON, with the ROC curves.")
+	flagSet.StringVar(&markdownPath, "markdown", "", "Where to save the report as Markdown. Printed if not given.")
+	comparisonFlags := registerComparisonFlags(flagSet, &options, COMPARISON_FLAGS...)
+	flagSet.Parse(args)
+
+	comparisonFlags.parse(&options)
+	if pairsPath == "" {
+		fmt.Println("Usage: hercules eval --pairs=<PAIRS_CSV> [--json=<REPORT_PATH>] [--markdown=<REPORT_PATH>]")
+		os.Exit(1)
+	}
+
+	report, err := workflow.Evaluate(pairsPath, options)
+	if err != nil {
+		fmt.Printf("Error evaluating: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonPath != "" {
+		if err := report.SaveJSON(jsonPath); err != nil {
+			fmt.Printf("Error saving the JSON report: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Saved the JSON report to %s\n", jsonPath)
+	}
+	if markdownPath == "" {
+		fmt.Print(report.Markdown())
+		return
+	}
+	if err := os.WriteFile(markdownPath, []byte(report.Markdown()), 0644); err != nil {
+		fmt.Printf("Error saving the Markdown report: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Saved the Markdown report to %s\n", markdownPath)
+}
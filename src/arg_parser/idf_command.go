@@ -0,0 +1,48 @@
+package arg_parser
+
+import (
+	"flag"
+	"fmt"
+	"hercules/src/workflow"
+	"os"
+)
+
+// idfCommand is `hercules idf build`, which builds a background IDF model from directories of repos
+func idfCommand(args []string) {
+	if len(args) == 0 || args[0] != "build" {
+		fmt.Println("Usage: hercules idf build --out=<MODEL_PATH> [--merge=<MODEL_PATH>] <DIR>...")
+		os.Exit(1)
+	}
+
+	var dirs stringSliceFlag
+	var mergePaths stringSliceFlag
+	var outPath string
+
+	flagSet := flag.NewFlagSet("idf build", flag.ExitOnError)
+	flagSet.Var(&dirs, "dir", "A directory of repos whose code files are counted. Can be repeated, or given as arguments.")
+	flagSet.Var(&mergePaths, "merge", "A model to add the counts to, e.g. to extend an existing model. Can be repeated.")
+	flagSet.StringVar(&outPath, "out", "", "Where to save the model.")
+	flagSet.Parse(args[1:])
+	dirs = append(dirs, flagSet.Args()...)
+
+	if outPath == "" {
+		fmt.Println("Please provide where to save the model using --out=<MODEL_PATH>")
+		os.Exit(1)
+	}
+	if len(dirs) == 0 && len(mergePaths) == 0 {
+		fmt.Println("Please provide at least one directory of repos or a model to merge.")
+		os.Exit(1)
+	}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			fmt.Printf("%s is not a directory.\n", dir)
+			os.Exit(1)
+		}
+	}
+
+	err := workflow.BuildBackgroundIDF(dirs, mergePaths, outPath)
+	if err != nil {
+		fmt.Printf("Error building IDF model: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -7,19 +7,79 @@ import (
 	"hercules/src/workflow"
 	"os"
 	"path/filepath"
+	"time"
 )
 
+// SUBCOMMANDS are run with the arguments after their name, e.g. `hercules idf build ...`
+var SUBCOMMANDS = map[string]func(args []string){
+	"cluster":   clusterCommand,
+	"eval":      evalCommand,
+	"idf":       idfCommand,
+	"index":     indexCommand,
+	"obfuscate": obfuscateCommand,
+	"train":     trainCommand,
+}
+
 func ArgParser() {
+	if len(os.Args) > 1 {
+		if subcommand, ok := SUBCOMMANDS[os.Args[1]]; ok {
+			subcommand(os.Args[2:])
+			return
+		}
+	}
+
 	// Define flags
 	var dir string
 	var url string
+	var excludedOwners stringSliceFlag
+	var studentEmails stringSliceFlag
+	var releaseDate string
+	options := workflow.DefaultOptions()
+	gitHubConfig := git_repo.GitHubConfigFromEnv()
 
+	comparisonFlags := registerComparisonFlags(flag.CommandLine, &options, COMPARISON_FLAGS...)
 	flag.StringVar(&dir, "dir", "", "The path to the directory.")
 	flag.StringVar(&url, "url", "", "The GitHub URL.")
+	flag.StringVar(&gitHubConfig.APIBaseURL, "github-api-url", gitHubConfig.APIBaseURL, "The GitHub API base URL, e.g. https://<ghes-host>/api/v3 for GitHub Enterprise Server.")
+	flag.StringVar(&gitHubConfig.WebBaseURL, "github-url", gitHubConfig.WebBaseURL, "The GitHub web base URL, e.g. https://<ghes-host> for GitHub Enterprise Server.")
+	flag.StringVar(&gitHubConfig.ProxyURL, "proxy", gitHubConfig.ProxyURL, "The HTTP(S) proxy URL. Defaults to HTTPS_PROXY/HTTP_PROXY.")
+	flag.StringVar(&gitHubConfig.CABundlePath, "ca-bundle", gitHubConfig.CABundlePath, "Path to a PEM CA bundle to trust in addition to the system roots.")
+	flag.StringVar(&options.CorpusIndexPath, "corpus-index", "", "A corpus index of repos on disk built with hercules index build, searched for candidate repos alongside GitHub.")
+	flag.BoolVar(&options.SkipGitHubSearch, "corpus-only", false, "Only search the corpus index, not GitHub.")
+	flag.StringVar(&options.IDFModelPath, "idf-model", "", "A background IDF model built with hercules idf build, to pick more distinctive search keywords.")
+	flag.BoolVar(&gitHubConfig.InsecureSkipVerify, "insecure-skip-verify", gitHubConfig.InsecureSkipVerify, "Skip TLS certificate verification.")
+	flag.StringVar(&options.SubmissionRepo, "submission-repo", "", "The owner/repo of the submission on GitHub. Detected from the origin remote for --dir.")
+	flag.StringVar(&options.Submitter, "submitter", "", "The GitHub user of the submitter. Their repos are never candidates. Defaults to the owner of --url.")
+	flag.Var(&excludedOwners, "exclude-owner", "A GitHub owner whose repos are never candidates, e.g. the course org. Can be repeated.")
+	flag.Var(&studentEmails, "student-email", "An author email of the student. Commits by other authors are flagged. Can be repeated. Defaults to the most frequent author.")
+	flag.StringVar(&releaseDate, "release-date", "", "When the assignment was released, as YYYY-MM-DD or RFC3339. Commits dated before are flagged.")
 
 	// Parse the flags
 	flag.Parse()
 
+	var err error
+	options.ExcludedOwners = excludedOwners
+	options.StudentEmails = studentEmails
+	comparisonFlags.parse(&options)
+	if options.SkipGitHubSearch && options.CorpusIndexPath == "" {
+		fmt.Println("Invalid --corpus-only: needs a --corpus-index to search")
+		os.Exit(1)
+	}
+	if releaseDate != "" {
+		parsedReleaseDate, err := parseDate(releaseDate)
+		if err != nil {
+			fmt.Printf("Invalid --release-date: %v\n", err)
+			os.Exit(1)
+		}
+		options.ReleaseDate = parsedReleaseDate
+	}
+
+	err = git_repo.Configure(gitHubConfig)
+	if err != nil {
+		fmt.Printf("Error configuring GitHub client: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Check if either flag was provided
 	if dir == "" && url == "" {
 		fmt.Println("Please provide either a directory path using --dir=<DIR_PATH> or a GitHub URL using --url=<GITHUB_URL>")
@@ -39,15 +99,46 @@ func ArgParser() {
 			os.Exit(1)
 		}
 		fmt.Println("Running execution on directory: " + absDir)
-		workflow.RunWorkflow(absDir, absDir, false)
+		if options.SubmissionRepo == "" {
+			options.SubmissionRepo, _ = git_repo.GetRepoNameFromGitRemote(absDir)
+		}
+		workflow.RunWorkflow(absDir, absDir, false, options)
 	}
 
 	if url != "" {
 		if git_repo.IsValidGitHubURL(url) {
-			workflow.RunGitCloneWorkflow(url)
+			workflow.RunGitCloneWorkflow(url, options)
 		} else {
-			fmt.Println("The provided URL is not a Github url or not valid.")
+			fmt.Println("The provided URL is not a Github url (" + gitHubConfig.WebBaseURL + ") or not valid.")
 			os.Exit(1)
 		}
 	}
 }
+
+func parseDate(date string) (time.Time, error) {
+	parsedDate, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+	if err == nil {
+		return parsedDate, nil
+	}
+	return time.Parse(time.RFC3339, date)
+}
+
+// parseLanguageFamilies returns the language families given by --language-family, or the default
+// ones for --cross-language, or none
+func parseLanguageFamilies(crossLanguage bool, languageFamilies []string) ([][]string, error) {
+	if len(languageFamilies) == 0 {
+		if crossLanguage {
+			return workflow.DEFAULT_LANGUAGE_FAMILIES, nil
+		}
+		return nil, nil
+	}
+	families := make([][]string, 0, len(languageFamilies))
+	for _, languageFamily := range languageFamilies {
+		family, err := workflow.ParseLanguageFamily(languageFamily)
+		if err != nil {
+			return nil, err
+		}
+		families = append(families, family)
+	}
+	return families, nil
+}
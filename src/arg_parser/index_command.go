@@ -0,0 +1,70 @@
+package arg_parser
+
+import (
+	"flag"
+	"fmt"
+	"hercules/src/minhash"
+	"hercules/src/workflow"
+	"os"
+)
+
+const INDEX_USAGE = `Usage:
+  hercules index build --out=<INDEX_PATH> [--lsh-bands=<N>] [--lsh-rows=<N>] <REPO_DIR>...
+  hercules index add --index=<INDEX_PATH> <REPO_DIR>...
+  hercules index remove --index=<INDEX_PATH> <REPO_DIR>...`
+
+// indexCommand is `hercules index build|add|remove`, which maintains a corpus index of repos on disk
+// that is searched for candidate repos with --corpus-index
+func indexCommand(args []string) {
+	if len(args) == 0 || (args[0] != "build" && args[0] != "add" && args[0] != "remove") {
+		fmt.Println(INDEX_USAGE)
+		os.Exit(1)
+	}
+	action := args[0]
+
+	var indexPath string
+	var bands int
+	var rows int
+
+	flagSet := flag.NewFlagSet("index "+action, flag.ExitOnError)
+	if action == "build" {
+		flagSet.StringVar(&indexPath, "out", "", "Where to save the index.")
+		flagSet.IntVar(&bands, "lsh-bands", minhash.DEFAULT_BANDS, "The bands of the LSH index of the files. More bands find less similar files.")
+		flagSet.IntVar(&rows, "lsh-rows", minhash.DEFAULT_ROWS, "The rows of every band of the LSH index. More rows find only more similar files.")
+	} else {
+		flagSet.StringVar(&indexPath, "index", "", "The index to change.")
+	}
+	flagSet.Parse(args[1:])
+	repoDirs := flagSet.Args()
+
+	if indexPath == "" || len(repoDirs) == 0 {
+		fmt.Println(INDEX_USAGE)
+		os.Exit(1)
+	}
+	if action != "remove" {
+		for _, repoDir := range repoDirs {
+			if info, err := os.Stat(repoDir); err != nil || !info.IsDir() {
+				fmt.Printf("%s is not a directory.\n", repoDir)
+				os.Exit(1)
+			}
+		}
+	}
+
+	var err error
+	switch action {
+	case "build":
+		if bands < 1 || rows < 1 {
+			fmt.Println("Invalid --lsh-bands or --lsh-rows: must be at least 1")
+			os.Exit(1)
+		}
+		err = workflow.BuildCorpusIndex(repoDirs, indexPath, bands, rows)
+	case "add":
+		err = workflow.AddToCorpusIndex(repoDirs, indexPath)
+	case "remove":
+		err = workflow.RemoveFromCorpusIndex(repoDirs, indexPath)
+	}
+	if err != nil {
+		fmt.Printf("Error updating the corpus index: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,40 @@
+package arg_parser
+
+import (
+	"flag"
+	"fmt"
+	"hercules/src/obfuscation"
+	"hercules/src/workflow"
+	"os"
+	"strings"
+)
+
+// obfuscateCommand is `hercules obfuscate`, which generates obfuscated copies of code files as labeled pairs
+func obfuscateCommand(args []string) {
+	var outDir string
+	var obfuscationsList string
+	var seed int64
+
+	flagSet := flag.NewFlagSet("obfuscate", flag.ExitOnError)
+	flagSet.StringVar(&outDir, "out", "", "The directory to write the originals, the obfuscated variants and pairs.csv to.")
+	flagSet.StringVar(&obfuscationsList, "obfuscations", strings.Join(obfuscation.ALL_OBFUSCATIONS, ","), "Comma separated obfuscations, from "+strings.Join(obfuscation.ALL_OBFUSCATIONS, ", ")+".")
+	flagSet.Int64Var(&seed, "seed", 1, "The random seed, so the same seed generates the same variants.")
+	flagSet.Parse(args)
+	inputs := flagSet.Args()
+
+	if outDir == "" || len(inputs) == 0 {
+		fmt.Println("Usage: hercules obfuscate --out=<OUT_DIR> [--obfuscations=<LIST>] [--seed=<SEED>] <FILE|DIR>...")
+		os.Exit(1)
+	}
+	obfuscations, err := obfuscation.ParseObfuscations(obfuscationsList)
+	if err != nil {
+		fmt.Printf("Invalid --obfuscations: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = workflow.GenerateObfuscatedPairs(inputs, outDir, obfuscations, seed)
+	if err != nil {
+		fmt.Printf("Error generating obfuscated pairs: %v\n", err)
+		os.Exit(1)
+	}
+}
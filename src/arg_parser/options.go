@@ -0,0 +1,108 @@
+package arg_parser
+
+import (
+	"flag"
+	"fmt"
+	"hercules/src/tfidf"
+	"hercules/src/util"
+	"hercules/src/workflow"
+	"os"
+	"strings"
+)
+
+// validateOptions exits if an option of the comparison is invalid. Every command starts from
+// workflow.DefaultOptions, so the options a command has no flag for are valid too.
+func validateOptions(options workflow.Options) {
+	if _, err := tfidf.ParseTokenizer(options.CLNATTokenizer); err != nil {
+		fmt.Printf("Invalid --clnat-tokenizer: %v\n", err)
+		os.Exit(1)
+	}
+	if options.GSTMinimumMatch < 1 {
+		fmt.Println("Invalid --gst-min-match: must be at least 1")
+		os.Exit(1)
+	}
+	if options.MaxMatchesPerFile < 1 {
+		fmt.Println("Invalid --max-matches-per-file: must be at least 1")
+		os.Exit(1)
+	}
+	if options.LSHBands < 1 || options.LSHRows < 1 {
+		fmt.Println("Invalid --lsh-bands or --lsh-rows: must be at least 1")
+		os.Exit(1)
+	}
+	if !util.Contains(workflow.WEIGHTINGS, options.WeightBy) {
+		fmt.Printf("Invalid --weight-by: expected one of %s\n", strings.Join(workflow.WEIGHTINGS, ", "))
+		os.Exit(1)
+	}
+}
+
+// COMPARISON_FLAGS are all the flags of how files are compared, for the commands that compare like a scan
+var COMPARISON_FLAGS = []string{
+	"base", "combine", "gst-min-match", "clnat-tokenizer", "fusion-model", "max-matches-per-file",
+	"cross-language", "language-family", "lsh", "lsh-bands", "lsh-rows", "weight-by",
+}
+
+// comparisonFlags are the raw values of the comparison flags that aren't options as they are
+type comparisonFlags struct {
+	baseSources      stringSliceFlag
+	combinedMetrics  string
+	crossLanguage    bool
+	languageFamilies stringSliceFlag
+	lsh              bool
+}
+
+// registerComparisonFlags defines the comparison flags of names on flagSet, with the defaults of options,
+// so every command describes them the same way
+func registerComparisonFlags(flagSet *flag.FlagSet, options *workflow.Options, names ...string) *comparisonFlags {
+	flags := &comparisonFlags{combinedMetrics: strings.Join(workflow.DEFAULT_COMBINED_METRICS, ",")}
+	for _, name := range names {
+		switch name {
+		case "base":
+			flagSet.Var(&flags.baseSources, name, "A directory or GitHub URL of the starter code of the assignment, which is discounted. Can be repeated.")
+		case "combine":
+			flagSet.StringVar(&flags.combinedMetrics, name, flags.combinedMetrics, "Comma separated metrics multiplied into the combined similarity, from "+strings.Join(workflow.ALL_METRICS, ", ")+".")
+		case "gst-min-match":
+			flagSet.IntVar(&options.GSTMinimumMatch, name, options.GSTMinimumMatch, "The minimum length in tokens of a greedy string tiling match.")
+		case "clnat-tokenizer":
+			flagSet.StringVar(&options.CLNATTokenizer, name, options.CLNATTokenizer, "The CLNAT tokenizer: char for non-letter characters, char:<n> for n-grams of them, or token:<n> for n-grams of normalized tokens.")
+		case "fusion-model":
+			flagSet.StringVar(&options.FusionModelPath, name, options.FusionModelPath, "A score fusion model trained with hercules train, to report the probability that the matches are plagiarised.")
+		case "max-matches-per-file":
+			flagSet.IntVar(&options.MaxMatchesPerFile, name, options.MaxMatchesPerFile, "How many files of the submission a file of a candidate repo can be matched with, e.g. 2 if files were split up.")
+		case "cross-language":
+			flagSet.BoolVar(&flags.crossLanguage, name, false, "Also compare files ported to another language of the default language families, by a language-neutral token stream.")
+		case "language-family":
+			flagSet.Var(&flags.languageFamilies, name, "Comma separated extensions of languages to compare files across, e.g. java,kt. Can be repeated. Implies --cross-language with only these families.")
+		case "lsh":
+			flagSet.BoolVar(&flags.lsh, name, false, "Only compare the pairs of files found by a MinHash LSH index, for large repos.")
+		case "lsh-bands":
+			flagSet.IntVar(&options.LSHBands, name, options.LSHBands, "The bands of the LSH index. More bands find less similar pairs.")
+		case "lsh-rows":
+			flagSet.IntVar(&options.LSHRows, name, options.LSHRows, "The rows of every band of the LSH index. More rows find only more similar pairs.")
+		case "weight-by":
+			flagSet.StringVar(&options.WeightBy, name, options.WeightBy, "What the weighted scores weigh the matched files by, from "+strings.Join(workflow.WEIGHTINGS, ", ")+".")
+		default:
+			panic("unknown comparison flag " + name)
+		}
+	}
+	return flags
+}
+
+// parse sets the options given by the raw flags once flagSet is parsed, exiting if any option is invalid
+func (flags *comparisonFlags) parse(options *workflow.Options) {
+	var err error
+	options.BaseSources = flags.baseSources
+	options.CombinedMetrics, err = workflow.ParseMetrics(flags.combinedMetrics)
+	if err != nil {
+		fmt.Printf("Invalid --combine: %v\n", err)
+		os.Exit(1)
+	}
+	options.LanguageFamilies, err = parseLanguageFamilies(flags.crossLanguage, flags.languageFamilies)
+	if err != nil {
+		fmt.Printf("Invalid --language-family: %v\n", err)
+		os.Exit(1)
+	}
+	validateOptions(*options)
+	if !flags.lsh {
+		options.LSHBands = 0
+	}
+}
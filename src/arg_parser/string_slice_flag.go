@@ -0,0 +1,15 @@
+package arg_parser
+
+import "strings"
+
+// stringSliceFlag is a flag that can be given multiple times, e.g. --exclude-owner=a --exclude-owner=b
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,49 @@
+package arg_parser
+
+import (
+	"flag"
+	"fmt"
+	"hercules/src/score_fusion"
+	"hercules/src/workflow"
+	"os"
+)
+
+// trainCommand is `hercules train`, which trains a score fusion model on labeled pairs of files
+func trainCommand(args []string) {
+	var pairsPath string
+	var outPath string
+	options := workflow.DefaultOptions()
+	trainOptions := score_fusion.DefaultTrainOptions()
+
+	flagSet := flag.NewFlagSet("train", flag.ExitOnError)
+	flagSet.StringVar(&pairsPath, "pairs", "", "A CSV file of labeled pairs with the header file1,file2,label, the label 1 for plagiarised pairs and 0 otherwise.")
+	flagSet.StringVar(&outPath, "out", "", "Where to save the model.")
+	flagSet.IntVar(&trainOptions.Epochs, "epochs", trainOptions.Epochs, "The number of gradient descent steps.")
+	flagSet.Float64Var(&trainOptions.LearningRate, "learning-rate", trainOptions.LearningRate, "The gradient descent step size.")
+	flagSet.Float64Var(&trainOptions.L2, "l2", trainOptions.L2, "The L2 regularization of the weights.")
+	flagSet.Float64Var(&trainOptions.HoldOutShare, "hold-out", trainOptions.HoldOutShare, "The share of the pairs held out of training to check the calibration on, 0 to train on all.")
+	flagSet.Int64Var(&trainOptions.Seed, "seed", trainOptions.Seed, "The seed of the hold-out split.")
+	// the model is used with the options it was trained with
+	comparisonFlags := registerComparisonFlags(flagSet, &options, "base", "gst-min-match", "clnat-tokenizer")
+	flagSet.Parse(args)
+
+	if pairsPath == "" || outPath == "" {
+		fmt.Println("Usage: hercules train --pairs=<PAIRS_CSV> --out=<MODEL_PATH>")
+		os.Exit(1)
+	}
+	if trainOptions.Epochs < 1 || trainOptions.LearningRate <= 0 || trainOptions.L2 < 0 {
+		fmt.Println("Invalid training options: --epochs must be at least 1, --learning-rate positive and --l2 not negative")
+		os.Exit(1)
+	}
+	if trainOptions.HoldOutShare < 0 || trainOptions.HoldOutShare >= 1 {
+		fmt.Println("Invalid --hold-out: must be at least 0 and below 1")
+		os.Exit(1)
+	}
+	comparisonFlags.parse(&options)
+
+	err := workflow.TrainFusionModel(pairsPath, outPath, trainOptions, options)
+	if err != nil {
+		fmt.Printf("Error training fusion model: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,275 @@
+package assignment
+
+import (
+	"hercules/src/util"
+	"math"
+	"sort"
+)
+
+const HUNGARIAN_MAX_SIZE = 400 // larger matrices are assigned greedily, as the Hungarian method is cubic
+
+// Assign matches the rows with the columns of weights so that the total weight of the matches is the
+// highest, every row matched at most once and every column at most capacity times. Only weights above
+// threshold are matched. It returns the matched column of every row, or -1, the same for the same weights.
+func Assign(weights [][]float64, threshold float64, capacity int) []int {
+	if capacity < 1 {
+		capacity = 1
+	}
+	rows := len(weights)
+	columns := 0
+	if rows > 0 {
+		columns = len(weights[0])
+	}
+	assigned := make([]int, rows)
+	for i := range assigned {
+		assigned[i] = -1
+	}
+	if rows == 0 || columns == 0 {
+		return assigned
+	}
+
+	var slots []int
+	if size := util.Max(rows, columns*capacity); size <= HUNGARIAN_MAX_SIZE {
+		slots = hungarian(weights, threshold, capacity, size)
+	} else {
+		slots = greedy(weights, threshold, capacity)
+	}
+	for i, slot := range slots {
+		if slot < 0 {
+			continue
+		}
+		column := slot / capacity
+		if weights[i][column] > threshold {
+			assigned[i] = column
+		}
+	}
+	return assigned
+}
+
+// Pair is a row and a column of the weights, with their weight
+type Pair struct {
+	Row    int
+	Column int
+	Weight float64
+}
+
+// AssignPairs is Assign for sparse weights: only the pairs have weights, all others are 0. The rows and
+// columns the pairs connect are assigned group by group, as a match in one group can't change another,
+// so no matrix of all rows and columns is built. It returns the matched column of every row, or -1.
+func AssignPairs(pairs []Pair, rows int, threshold float64, capacity int) []int {
+	if capacity < 1 {
+		capacity = 1
+	}
+	assigned := make([]int, rows)
+	for i := range assigned {
+		assigned[i] = -1
+	}
+	for _, group := range connectedGroups(util.Filter(pairs, func(pair Pair) bool {
+		return pair.Weight > threshold
+	})) {
+		groupRows, groupColumns := groupIndexes(group)
+		if util.Max(len(groupRows), len(groupColumns)*capacity) > HUNGARIAN_MAX_SIZE {
+			greedyPairs(group, capacity, assigned)
+			continue
+		}
+		// the weights of the group alone, by position in groupRows and groupColumns
+		rowPositions := positions(groupRows)
+		columnPositions := positions(groupColumns)
+		weights := make([][]float64, len(groupRows))
+		for i := range weights {
+			weights[i] = make([]float64, len(groupColumns))
+		}
+		for _, pair := range group {
+			weights[rowPositions[pair.Row]][columnPositions[pair.Column]] = pair.Weight
+		}
+		for i, column := range Assign(weights, threshold, capacity) {
+			if column >= 0 {
+				assigned[groupRows[i]] = groupColumns[column]
+			}
+		}
+	}
+	return assigned
+}
+
+// connectedGroups splits the pairs into the groups of pairs connected by a shared row or column,
+// in order of their first pair, the pairs of a group in their order
+func connectedGroups(pairs []Pair) [][]Pair {
+	// union-find over the rows and the columns, a column stored as -1-column
+	parents := make(map[int]int)
+	var find func(node int) int
+	find = func(node int) int {
+		parent, ok := parents[node]
+		if !ok || parent == node {
+			return node
+		}
+		parents[node] = find(parent)
+		return parents[node]
+	}
+	for _, pair := range pairs {
+		parents[find(pair.Row)] = find(-1 - pair.Column)
+	}
+
+	var groups [][]Pair
+	groupIndexes := make(map[int]int) // by root
+	for _, pair := range pairs {
+		root := find(pair.Row)
+		index, ok := groupIndexes[root]
+		if !ok {
+			index = len(groups)
+			groupIndexes[root] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], pair)
+	}
+	return groups
+}
+
+// groupIndexes are the rows and the columns of the pairs, sorted
+func groupIndexes(pairs []Pair) ([]int, []int) {
+	rowSet := make(map[int]bool)
+	columnSet := make(map[int]bool)
+	for _, pair := range pairs {
+		rowSet[pair.Row] = true
+		columnSet[pair.Column] = true
+	}
+	return sortedKeys(rowSet), sortedKeys(columnSet)
+}
+
+func sortedKeys(set map[int]bool) []int {
+	keys := make([]int, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// positions maps every index to its position in indexes
+func positions(indexes []int) map[int]int {
+	positionMap := make(map[int]int, len(indexes))
+	for position, index := range indexes {
+		positionMap[index] = position
+	}
+	return positionMap
+}
+
+// hungarian solves the assignment on a size x size matrix, with every column repeated capacity times,
+// capacity slots in a row, and weights not above threshold as 0. It returns the slot of every row.
+func hungarian(weights [][]float64, threshold float64, capacity int, size int) []int {
+	// costs, 1-based as in the shortest augmenting path formulation with potentials
+	cost := func(row int, slot int) float64 {
+		if row > len(weights) || (slot-1)/capacity >= len(weights[0]) {
+			return 0
+		}
+		weight := weights[row-1][(slot-1)/capacity]
+		if weight <= threshold {
+			return 0
+		}
+		return -weight
+	}
+
+	rowPotentials := make([]float64, size+1)
+	slotPotentials := make([]float64, size+1)
+	slotRows := make([]int, size+1) // the row matched with every slot, 0 if none
+	way := make([]int, size+1)
+	for row := 1; row <= size; row++ {
+		slotRows[0] = row
+		slot := 0
+		minimums := make([]float64, size+1)
+		for i := range minimums {
+			minimums[i] = math.Inf(1)
+		}
+		used := make([]bool, size+1)
+		for {
+			used[slot] = true
+			currentRow := slotRows[slot]
+			delta := math.Inf(1)
+			nextSlot := 0
+			for j := 1; j <= size; j++ {
+				if used[j] {
+					continue
+				}
+				reducedCost := cost(currentRow, j) - rowPotentials[currentRow] - slotPotentials[j]
+				if reducedCost < minimums[j] {
+					minimums[j] = reducedCost
+					way[j] = slot
+				}
+				if minimums[j] < delta {
+					delta = minimums[j]
+					nextSlot = j
+				}
+			}
+			for j := 0; j <= size; j++ {
+				if used[j] {
+					rowPotentials[slotRows[j]] += delta
+					slotPotentials[j] -= delta
+				} else {
+					minimums[j] -= delta
+				}
+			}
+			slot = nextSlot
+			if slotRows[slot] == 0 {
+				break
+			}
+		}
+		for slot != 0 {
+			previousSlot := way[slot]
+			slotRows[slot] = slotRows[previousSlot]
+			slot = previousSlot
+		}
+	}
+
+	slots := make([]int, len(weights))
+	for i := range slots {
+		slots[i] = -1
+	}
+	for slot := 1; slot <= size; slot++ {
+		if row := slotRows[slot]; row >= 1 && row <= len(weights) && (slot-1)/capacity < len(weights[0]) {
+			slots[row-1] = slot - 1
+		}
+	}
+	return slots
+}
+
+// greedy matches the pairs from the highest weight down, while the row and the column are free.
+// It returns the slot of every row, column*capacity as greedy doesn't need the slots apart.
+func greedy(weights [][]float64, threshold float64, capacity int) []int {
+	var pairs []Pair
+	for row := range weights {
+		for column, weight := range weights[row] {
+			if weight > threshold {
+				pairs = append(pairs, Pair{Row: row, Column: column, Weight: weight})
+			}
+		}
+	}
+	assigned := make([]int, len(weights))
+	for i := range assigned {
+		assigned[i] = -1
+	}
+	greedyPairs(pairs, capacity, assigned)
+
+	slots := make([]int, len(weights))
+	for row, column := range assigned {
+		slots[row] = -1
+		if column >= 0 {
+			slots[row] = column * capacity
+		}
+	}
+	return slots
+}
+
+// greedyPairs matches the pairs from the highest weight down, while the row and the column are free,
+// setting the matched column of their rows in assigned
+func greedyPairs(pairs []Pair, capacity int, assigned []int) {
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Weight > pairs[j].Weight
+	})
+	matches := make(map[int]int)
+	for _, pair := range pairs {
+		if assigned[pair.Row] >= 0 || matches[pair.Column] >= capacity {
+			continue
+		}
+		assigned[pair.Row] = pair.Column
+		matches[pair.Column]++
+	}
+}
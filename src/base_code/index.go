@@ -0,0 +1,122 @@
+package base_code
+
+import (
+	"fmt"
+	"hercules/src/git_repo"
+	"hercules/src/util"
+	"os"
+	"strings"
+)
+
+const BASE_FILE_SHARE = 0.9 // files with this share of base lines are base files
+const BASE_TEXT_MAX_LENGTH = 1000000
+
+// BaseCode is the starter code shipped with an assignment, kept as a set of
+// trimmed lines so it can be recognised however it was re-indented.
+// A nil *BaseCode has no lines, so callers don't need to check for it.
+type BaseCode struct {
+	lines map[string]bool
+}
+
+// Load reads the code files of every source, which is either a local directory or a GitHub URL.
+func Load(sources []string) (*BaseCode, error) {
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	baseCode := &BaseCode{lines: make(map[string]bool)}
+	for _, source := range sources {
+		dir := source
+		if git_repo.IsValidGitHubURL(source) {
+			tempDir, err := os.MkdirTemp("", util.TEMP_REPO_PREFIX)
+			if err != nil {
+				return nil, err
+			}
+			defer util.Cleanup(tempDir)
+			git_repo.GitClone(source, tempDir)
+			dir = tempDir
+		} else if info, err := os.Stat(source); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("base %s is neither a directory nor a GitHub URL", source)
+		}
+
+		filePaths, err := util.GetFilePaths(dir)
+		if err != nil {
+			return nil, err
+		}
+		filePaths = util.RemoveNonCodeFiles(filePaths)
+		allDataMap, err := util.MultipleFileRead(filePaths, BASE_TEXT_MAX_LENGTH)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range allDataMap {
+			baseCode.AddText(data)
+		}
+	}
+	return baseCode, nil
+}
+
+func (baseCode *BaseCode) AddText(text string) {
+	for _, line := range strings.Split(text, "\n") {
+		if normalizedLine, ok := normalizeLine(line); ok {
+			baseCode.lines[normalizedLine] = true
+		}
+	}
+}
+
+func (baseCode *BaseCode) IsBaseLine(line string) bool {
+	if baseCode == nil {
+		return false
+	}
+	normalizedLine, ok := normalizeLine(line)
+	return ok && baseCode.lines[normalizedLine]
+}
+
+// StripBaseLines removes the lines of text that are in the base code
+func (baseCode *BaseCode) StripBaseLines(text string) string {
+	if baseCode == nil {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	keptLines := util.Filter(lines, func(line string) bool {
+		return !baseCode.IsBaseLine(line)
+	})
+	return strings.Join(keptLines, "\n")
+}
+
+// BaseShare returns the share of the significant lines of text[startIndex:endIndex] that are base code
+func (baseCode *BaseCode) BaseShare(text string, startIndex int, endIndex int) float64 {
+	if baseCode == nil {
+		return 0
+	}
+	startIndex = util.Max(0, util.Min(startIndex, len(text)))
+	endIndex = util.Max(startIndex, util.Min(endIndex, len(text)))
+
+	significantLines := 0
+	baseLines := 0
+	for _, line := range strings.Split(text[startIndex:endIndex], "\n") {
+		if _, ok := normalizeLine(line); !ok {
+			continue
+		}
+		significantLines++
+		if baseCode.IsBaseLine(line) {
+			baseLines++
+		}
+	}
+	if significantLines == 0 {
+		return 0
+	}
+	return float64(baseLines) / float64(significantLines)
+}
+
+// IsBaseFile is true if (nearly) all of text is base code
+func (baseCode *BaseCode) IsBaseFile(text string) bool {
+	if baseCode == nil {
+		return false
+	}
+	return baseCode.BaseShare(text, 0, len(text)) >= BASE_FILE_SHARE
+}
+
+func normalizeLine(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	return line, util.IsSignificantLine(line)
+}
@@ -0,0 +1,172 @@
+package clustering
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// WriteDOT writes the graph for Graphviz, the clusters as subgraphs and the edges not above threshold dashed
+func WriteDOT(writer io.Writer, graph *Graph, clusters []Cluster, threshold float64) error {
+	origins := make(map[string]bool)
+	for _, cluster := range clusters {
+		origins[cluster.Origin] = true
+	}
+	writeNode := func(node Node, indent string) {
+		attributes := fmt.Sprintf("label=%s", strconv.Quote(node.Label))
+		if node.External {
+			attributes += ", shape=box"
+		}
+		if origins[node.ID] {
+			attributes += ", style=bold"
+		}
+		fmt.Fprintf(writer, "%s%s [%s];\n", indent, strconv.Quote(node.ID), attributes)
+	}
+
+	fmt.Fprintln(writer, "graph cohort {")
+	clusterIDs := clusterOf(clusters)
+	nodes := make(map[string]Node, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = node
+	}
+	for _, cluster := range clusters {
+		fmt.Fprintf(writer, "  subgraph cluster_%d {\n", cluster.ID)
+		fmt.Fprintf(writer, "    label=%s;\n", strconv.Quote(fmt.Sprintf("cluster %d, origin %s", cluster.ID, nodes[cluster.Origin].Label)))
+		for _, id := range cluster.Members {
+			writeNode(nodes[id], "    ")
+		}
+		fmt.Fprintln(writer, "  }")
+	}
+	for _, node := range graph.Nodes {
+		if _, ok := clusterIDs[node.ID]; !ok {
+			writeNode(node, "  ")
+		}
+	}
+	for _, edge := range graph.Edges {
+		style := ""
+		if edge.Similarity <= threshold {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(writer, "  %s -- %s [label=\"%.2f\"%s];\n", strconv.Quote(edge.Source), strconv.Quote(edge.Target), edge.Similarity, style)
+	}
+	_, err := fmt.Fprintln(writer, "}")
+	return err
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// WriteGraphML writes the graph as GraphML, e.g. for Gephi or yEd, with the cluster of every node, 0 if
+// none, and whether it is the origin of its cluster
+func WriteGraphML(writer io.Writer, graph *Graph, clusters []Cluster) error {
+	document := graphMLDocument{XMLNS: "http://graphml.graphdrawing.org/xmlns"}
+	document.Keys = []graphMLKey{
+		{ID: "label", For: "node", Name: "label", Type: "string"},
+		{ID: "external", For: "node", Name: "external", Type: "boolean"},
+		{ID: "cluster", For: "node", Name: "cluster", Type: "int"},
+		{ID: "origin", For: "node", Name: "origin", Type: "boolean"},
+		{ID: "similarity", For: "edge", Name: "similarity", Type: "double"},
+		{ID: "older", For: "edge", Name: "older", Type: "string"},
+	}
+	document.Graph.ID = "cohort"
+	document.Graph.EdgeDefault = "undirected"
+
+	clusterIDs := clusterOf(clusters)
+	origins := make(map[string]bool)
+	for _, cluster := range clusters {
+		origins[cluster.Origin] = true
+	}
+	for _, node := range graph.Nodes {
+		document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: node.Label},
+				{Key: "external", Value: strconv.FormatBool(node.External)},
+				{Key: "cluster", Value: strconv.Itoa(clusterIDs[node.ID])},
+				{Key: "origin", Value: strconv.FormatBool(origins[node.ID])},
+			},
+		})
+	}
+	for _, edge := range graph.Edges {
+		data := []graphMLData{{Key: "similarity", Value: strconv.FormatFloat(edge.Similarity, 'f', 4, 64)}}
+		if edge.Older != "" {
+			data = append(data, graphMLData{Key: "older", Value: edge.Older})
+		}
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{Source: edge.Source, Target: edge.Target, Data: data})
+	}
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
+// WriteJSON writes the graph and the clusters as JSON
+func WriteJSON(writer io.Writer, graph *Graph, clusters []Cluster, method string, threshold float64) error {
+	data, err := json.MarshalIndent(struct {
+		Method    string    `json:"method"`
+		Threshold float64   `json:"threshold"`
+		Nodes     []Node    `json:"nodes"`
+		Edges     []Edge    `json:"edges"`
+		Clusters  []Cluster `json:"clusters"`
+	}{method, threshold, graph.Nodes, graph.Edges, clusters}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+// SaveFile creates the file at path and writes it with write
+func SaveFile(path string, write func(writer io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := write(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,255 @@
+package clustering
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	METHOD_COMPONENTS   = "components"
+	METHOD_HIERARCHICAL = "hierarchical"
+)
+
+var METHODS = []string{METHOD_COMPONENTS, METHOD_HIERARCHICAL}
+
+const (
+	ORIGIN_EXTERNAL     = "external repo"
+	ORIGIN_OLDEST       = "oldest by git history"
+	ORIGIN_MOST_SIMILAR = "most similar to the others"
+)
+
+// Node is a submission or an external repo, e.g. a known solution
+type Node struct {
+	ID       string `json:"id"` // the repo's directory
+	Label    string `json:"label"`
+	External bool   `json:"external"`
+}
+
+// Edge is the combined similarity of two repos
+type Edge struct {
+	Source     string  `json:"source"`
+	Target     string  `json:"target"`
+	Similarity float64 `json:"similarity"`
+	Older      string  `json:"older,omitempty"` // the repo whose matched code appeared first by git history, if known
+}
+
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Cluster is a group of repos that share code, with the repo it most likely came from
+type Cluster struct {
+	ID             int      `json:"id"`
+	Members        []string `json:"members"`
+	Origin         string   `json:"origin"`
+	OriginReason   string   `json:"origin_reason"`
+	MeanSimilarity float64  `json:"mean_similarity"` // of the edges between the members
+}
+
+// Cluster groups the nodes of the graph with method, see connectedComponents and hierarchical
+func (graph *Graph) Cluster(method string, threshold float64) ([]Cluster, error) {
+	var groups [][]string
+	switch method {
+	case METHOD_COMPONENTS:
+		groups = graph.connectedComponents(threshold)
+	case METHOD_HIERARCHICAL:
+		groups = graph.hierarchical(threshold)
+	default:
+		return nil, fmt.Errorf("unknown clustering method %q, expected one of %v", method, METHODS)
+	}
+	return graph.namedClusters(groups), nil
+}
+
+// connectedComponents are the groups of nodes connected by edges above threshold, of at least two nodes
+func (graph *Graph) connectedComponents(threshold float64) [][]string {
+	parents := make(map[string]string, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		parents[node.ID] = node.ID
+	}
+	var find func(id string) string
+	find = func(id string) string {
+		if parents[id] != id {
+			parents[id] = find(parents[id])
+		}
+		return parents[id]
+	}
+	for _, edge := range graph.Edges {
+		if edge.Similarity > threshold {
+			parents[find(edge.Source)] = find(edge.Target)
+		}
+	}
+
+	members := make(map[string][]string)
+	for _, node := range graph.Nodes {
+		root := find(node.ID)
+		members[root] = append(members[root], node.ID)
+	}
+	var groups [][]string
+	for _, group := range members {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// hierarchical merges the two groups of nodes with the highest average similarity of their pairs, pairs
+// without an edge as 0, until no two groups are above threshold. It returns the groups of at least two
+// nodes. Unlike connected components, a chain of pairs that are each just above threshold isn't one group.
+func (graph *Graph) hierarchical(threshold float64) [][]string {
+	similarities := graph.similarities()
+	groups := make([][]string, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		groups[i] = []string{node.ID}
+	}
+	averageLinkage := func(group1 []string, group2 []string) float64 {
+		total := 0.0
+		for _, id1 := range group1 {
+			for _, id2 := range group2 {
+				total += similarities[id1][id2]
+			}
+		}
+		return total / float64(len(group1)*len(group2))
+	}
+
+	for len(groups) > 1 {
+		best1, best2, bestLinkage := -1, -1, threshold
+		for i := range groups {
+			for j := i + 1; j < len(groups); j++ {
+				if linkage := averageLinkage(groups[i], groups[j]); linkage > bestLinkage {
+					best1, best2, bestLinkage = i, j, linkage
+				}
+			}
+		}
+		if best1 < 0 {
+			break
+		}
+		groups[best1] = append(groups[best1], groups[best2]...)
+		groups = append(groups[:best2], groups[best2+1:]...)
+	}
+
+	var multiMemberGroups [][]string
+	for _, group := range groups {
+		if len(group) > 1 {
+			multiMemberGroups = append(multiMemberGroups, group)
+		}
+	}
+	return multiMemberGroups
+}
+
+// namedClusters sorts the members of the groups and the groups by their size, and names their origins
+func (graph *Graph) namedClusters(groups [][]string) []Cluster {
+	for _, group := range groups {
+		sort.Strings(group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if len(groups[i]) != len(groups[j]) {
+			return len(groups[i]) > len(groups[j])
+		}
+		return groups[i][0] < groups[j][0]
+	})
+
+	clusters := make([]Cluster, len(groups))
+	for i, group := range groups {
+		clusters[i] = Cluster{ID: i + 1, Members: group}
+		clusters[i].Origin, clusters[i].OriginReason = graph.origin(group)
+		clusters[i].MeanSimilarity = graph.meanSimilarity(group)
+	}
+	return clusters
+}
+
+// origin picks the repo the others most likely copied from: an external repo if the group has any (the
+// one most similar to the others), else the repo that was older in the most pairs by git history, else
+// the repo most similar to the others, as copies of one source are each closest to the source
+func (graph *Graph) origin(group []string) (string, string) {
+	inGroup := make(map[string]bool, len(group))
+	for _, id := range group {
+		inGroup[id] = true
+	}
+	external := make(map[string]bool)
+	for _, node := range graph.Nodes {
+		if node.External {
+			external[node.ID] = true
+		}
+	}
+	similarityTotals := make(map[string]float64)
+	olderCounts := make(map[string]int)
+	for _, edge := range graph.Edges {
+		if !inGroup[edge.Source] || !inGroup[edge.Target] {
+			continue
+		}
+		similarityTotals[edge.Source] += edge.Similarity
+		similarityTotals[edge.Target] += edge.Similarity
+		if edge.Older != "" {
+			olderCounts[edge.Older]++
+		}
+	}
+
+	best := func(candidates []string, score func(id string) float64) string {
+		bestID := candidates[0]
+		for _, id := range candidates[1:] {
+			if score(id) > score(bestID) {
+				bestID = id
+			}
+		}
+		return bestID
+	}
+	bySimilarity := func(id string) float64 { return similarityTotals[id] }
+
+	var externalMembers []string
+	for _, id := range group {
+		if external[id] {
+			externalMembers = append(externalMembers, id)
+		}
+	}
+	if len(externalMembers) > 0 {
+		return best(externalMembers, bySimilarity), ORIGIN_EXTERNAL
+	}
+	if oldest := best(group, func(id string) float64 { return float64(olderCounts[id]) }); olderCounts[oldest] > 0 {
+		return oldest, ORIGIN_OLDEST
+	}
+	return best(group, bySimilarity), ORIGIN_MOST_SIMILAR
+}
+
+func (graph *Graph) meanSimilarity(group []string) float64 {
+	inGroup := make(map[string]bool, len(group))
+	for _, id := range group {
+		inGroup[id] = true
+	}
+	total, count := 0.0, 0
+	for _, edge := range graph.Edges {
+		if inGroup[edge.Source] && inGroup[edge.Target] {
+			total += edge.Similarity
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / float64(count)
+}
+
+// similarities are the similarities of the edges by both their nodes
+func (graph *Graph) similarities() map[string]map[string]float64 {
+	similarities := make(map[string]map[string]float64, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		similarities[node.ID] = make(map[string]float64)
+	}
+	for _, edge := range graph.Edges {
+		similarities[edge.Source][edge.Target] = edge.Similarity
+		similarities[edge.Target][edge.Source] = edge.Similarity
+	}
+	return similarities
+}
+
+// clusterOf is the ID of the cluster of every clustered node
+func clusterOf(clusters []Cluster) map[string]int {
+	clusterIDs := make(map[string]int)
+	for _, cluster := range clusters {
+		for _, id := range cluster.Members {
+			clusterIDs[id] = cluster.ID
+		}
+	}
+	return clusterIDs
+}
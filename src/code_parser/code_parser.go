@@ -2,8 +2,10 @@ package code_parser
 
 import (
 	"fmt"
+	"hercules/src/lexer"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 type LineMetaObject struct {
@@ -13,9 +15,52 @@ type LineMetaObject struct {
 }
 
 type ParsedCodeTextObject struct {
-	ParsedCodeText string
-	LineMeta       []LineMetaObject
-	SortedKeys     []int
+	ParsedCodeText   string
+	LineMeta         []LineMetaObject
+	SortedKeys       []int
+	ParsedLineStarts []int // index in ParsedCodeText where each line starts
+
+	// only set by ParseCode for languages with a lexer, and by ParseNeutralCode
+	SourceMap          []int // index in the original text of every index in ParsedCodeText
+	OriginalLineStarts []int // index in the original text where each line starts
+	Tokens             []lexer.Token
+
+	originalLength int // length of the original text, which the end of ParsedCodeText maps to
+}
+
+// ParseCode normalizes text with the lexer of the file's language, so that renamed
+// identifiers, changed literals and comments don't matter. Files in other languages
+// fall back to ParseCodeText.
+func ParseCode(text string, path string) *ParsedCodeTextObject {
+	language := lexer.LanguageFromPath(path)
+	if language == nil {
+		return ParseCodeText(text)
+	}
+
+	return parseNormalizedText(text, lexer.Normalize(language, text))
+}
+
+// ParseNeutralCode writes text out as the language-neutral token stream of lexer.Neutralize, so that
+// a file can be compared with one ported to another language. Files in languages without a lexer are
+// lexed with lexer.GENERIC.
+func ParseNeutralCode(text string, path string) *ParsedCodeTextObject {
+	language := lexer.LanguageFromPath(path)
+	if language == nil {
+		language = lexer.GENERIC
+	}
+	return parseNormalizedText(text, lexer.Neutralize(language, text))
+}
+
+func parseNormalizedText(text string, normalizedText *lexer.NormalizedText) *ParsedCodeTextObject {
+	parsedCodeTextObject := ParsedCodeTextObject{
+		ParsedCodeText:     normalizedText.Text,
+		ParsedLineStarts:   findLineStarts(normalizedText.Text),
+		SourceMap:          normalizedText.SourceMap,
+		OriginalLineStarts: findLineStarts(text),
+		Tokens:             normalizedText.Tokens,
+		originalLength:     len(text),
+	}
+	return &parsedCodeTextObject
 }
 
 func ParseCodeText(text string) *ParsedCodeTextObject {
@@ -31,10 +76,12 @@ func ParseCodeText(text string) *ParsedCodeTextObject {
 
 	for i, char := range text {
 		isWhitespace := char == ' ' || char == '\t'
+		// i is a byte offset, so the last rune ends at len(text), not at i+1
+		isLastRune := i+utf8.RuneLen(char) == len(text) || char == utf8.RuneError && i+1 == len(text)
 
-		if char == '\n' || i == len(text)-1 {
-			if i == len(text)-1 && !isWhitespace {
-				parsedText.WriteString(text[lineStart : i+1])
+		if char == '\n' || isLastRune {
+			if isLastRune && char != '\n' && !isWhitespace {
+				parsedText.WriteString(text[lineStart+leadingWhitespaceCount : len(text)])
 			} else {
 				parsedText.WriteString(text[lineStart+leadingWhitespaceCount : i-trailingWhitespaceCount])
 			}
@@ -71,13 +118,58 @@ func ParseCodeText(text string) *ParsedCodeTextObject {
 	}
 
 	parsedCodeTextObject := ParsedCodeTextObject{
-		ParsedCodeText: parsedText.String(),
-		LineMeta:       lineMeta,
-		SortedKeys:     sortedKeys,
+		ParsedCodeText:   parsedText.String(),
+		LineMeta:         lineMeta,
+		SortedKeys:       sortedKeys,
+		ParsedLineStarts: findLineStarts(parsedText.String()),
+		originalLength:   len(text),
 	}
 	return &parsedCodeTextObject
 }
 
+func findLineStarts(text string) []int {
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' && i+1 < len(text) {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return lineStarts
+}
+
+// FindLineNumber returns the 1-based line number in the original text of parsedIndex
+func (parsedCodeTextObject *ParsedCodeTextObject) FindLineNumber(parsedIndex int) int {
+	if parsedCodeTextObject.SourceMap != nil {
+		originalIndex := parsedCodeTextObject.FindOriginalIndex(parsedIndex)
+		lineStarts := parsedCodeTextObject.OriginalLineStarts
+		return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > originalIndex })
+	}
+	// ParseCodeText keeps every line, so the line number is the same in the parsed and the original text
+	lineStarts := parsedCodeTextObject.ParsedLineStarts
+	return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > parsedIndex })
+}
+
+// FindOriginalLineAndColumn returns the 1-based line and column (in bytes) in the original text of parsedIndex
+func (parsedCodeTextObject *ParsedCodeTextObject) FindOriginalLineAndColumn(parsedIndex int) (int, int) {
+	if parsedCodeTextObject.SourceMap == nil {
+		line := parsedCodeTextObject.FindLineNumber(parsedIndex)
+		if parsedIndex < 0 || parsedIndex > len(parsedCodeTextObject.ParsedCodeText) {
+			return 0, 0 // parsedIndex is out of range
+		}
+		column := parsedIndex - parsedCodeTextObject.ParsedLineStarts[line-1] + 1
+		if line-1 < len(parsedCodeTextObject.LineMeta) {
+			column += parsedCodeTextObject.LineMeta[line-1].LeadingWhitespaceCount
+		}
+		return line, column
+	}
+	originalIndex := parsedCodeTextObject.FindOriginalIndex(parsedIndex)
+	line := parsedCodeTextObject.FindLineNumber(parsedIndex)
+	if originalIndex < 0 || line < 1 || line > len(parsedCodeTextObject.OriginalLineStarts) {
+		return 0, 0 // parsedIndex is out of range
+	}
+	return line, originalIndex - parsedCodeTextObject.OriginalLineStarts[line-1] + 1
+}
+
 func (parsedCodeTextObject *ParsedCodeTextObject) FindLineStart(index int) (int, int, error) {
 	// Find the line that contains index
 	sortedKeys := parsedCodeTextObject.SortedKeys
@@ -92,25 +184,58 @@ func (parsedCodeTextObject *ParsedCodeTextObject) FindLineStart(index int) (int,
 
 func (parsedCodeTextObject *ParsedCodeTextObject) FindOriginalIndex(parsedIndex int) int {
 	// Converts the parsedIndex to the original index
+	if parsedCodeTextObject.SourceMap != nil {
+		sourceMap := parsedCodeTextObject.SourceMap
+		if parsedIndex < 0 || parsedIndex >= len(sourceMap) {
+			return -1
+		}
+		return sourceMap[parsedIndex]
+	}
+
 	sortedKeys := parsedCodeTextObject.SortedKeys
 	lineMeta := parsedCodeTextObject.LineMeta
+	parsedLineStarts := parsedCodeTextObject.ParsedLineStarts
 
-	lineIndex := sort.Search(len(sortedKeys), func(i int) bool {
-		if i == len(sortedKeys)-1 {
-			return true
-		}
-		return sortedKeys[i+1] > parsedIndex
-	})
+	if parsedIndex < 0 || parsedIndex > len(parsedCodeTextObject.ParsedCodeText) {
+		return -1
+	}
+	if parsedIndex == len(parsedCodeTextObject.ParsedCodeText) {
+		return parsedCodeTextObject.originalLength
+	}
+
+	// the line is found by where it starts in parsedText, sortedKeys are where it starts in the original text
+	lineIndex := sort.Search(len(parsedLineStarts), func(i int) bool { return parsedLineStarts[i] > parsedIndex }) - 1
 
 	if lineIndex >= len(lineMeta) || lineIndex < 0 {
 		return -1
 	}
 
 	// Calculate the difference between parsedIndex and the start of that line in parsedText
-	diff := parsedIndex - sortedKeys[lineIndex]
+	diff := parsedIndex - parsedLineStarts[lineIndex]
 
 	// Adjust for leading whitespaces to find the original index
 	originalIndex := sortedKeys[lineIndex] + lineMeta[lineIndex].LeadingWhitespaceCount + diff
 
 	return originalIndex
 }
+
+// TokenStream returns the normalized tokens and their 1-based line in the original text.
+// Without a lexer the words of every line are the tokens.
+func (parsedCodeTextObject *ParsedCodeTextObject) TokenStream() ([]string, []int) {
+	var tokens []string
+	var lines []int
+	if parsedCodeTextObject.Tokens != nil {
+		for _, token := range parsedCodeTextObject.Tokens {
+			tokens = append(tokens, token.Normalized())
+			lines = append(lines, token.Line)
+		}
+		return tokens, lines
+	}
+	for i, line := range strings.Split(parsedCodeTextObject.ParsedCodeText, "\n") {
+		for _, word := range strings.Fields(line) {
+			tokens = append(tokens, word)
+			lines = append(lines, i+1)
+		}
+	}
+	return tokens, lines
+}
@@ -0,0 +1,76 @@
+package code_units
+
+import (
+	"regexp"
+	"strings"
+)
+
+// UNIT_HEADER matches the first line of a function in languages without a parser, by the keyword
+// that declares it, e.g. `def`, `fn`, `fun`, `func`, `function`, `sub` or `proc`
+var UNIT_HEADER = regexp.MustCompile(`^\s*(?:[\w@]+\s+)*(?:def|fn|fun|func|function|sub|proc)\s+[\w$.!?]+`)
+
+// heuristicFunctionRanges finds the functions by their headers. A function with a `{` on its header
+// line or the next ends where the braces balance, and any other where the indentation returns to the
+// header's, with an `end` at that indentation, e.g. in Ruby, included.
+func heuristicFunctionRanges(lines []string) [][2]int {
+	var ranges [][2]int
+	for i := 0; i < len(lines); i++ {
+		if !UNIT_HEADER.MatchString(lines[i]) {
+			continue
+		}
+		end := braceBlockEnd(lines, i)
+		if end < 0 {
+			end = indentedBlockEnd(lines, i)
+		}
+		if end > i {
+			ranges = append(ranges, [2]int{i + 1, end + 1})
+			i = end // nested functions are part of this one
+		}
+	}
+	return ranges
+}
+
+// braceBlockEnd is the index of the line where the braces opened on the header line, or on the next,
+// are closed, or -1 if there is no opening brace or it is never closed
+func braceBlockEnd(lines []string, header int) int {
+	opened := strings.Contains(lines[header], "{") ||
+		(header+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[header+1]), "{"))
+	if !opened {
+		return -1
+	}
+	depth := 0
+	seenBrace := false
+	for i := header; i < len(lines); i++ {
+		openings := strings.Count(lines[i], "{")
+		depth += openings - strings.Count(lines[i], "}")
+		seenBrace = seenBrace || openings > 0
+		if seenBrace && depth <= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// indentedBlockEnd is the index of the last line indented deeper than the header, or of the `end`
+// closing it
+func indentedBlockEnd(lines []string, header int) int {
+	headerIndentation := indentationWidth(lines[header])
+	end := header
+	for i := header + 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+		if indentationWidth(lines[i]) <= headerIndentation {
+			if strings.HasPrefix(strings.TrimSpace(lines[i]), "end") {
+				return i
+			}
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+func indentationWidth(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
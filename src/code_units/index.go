@@ -0,0 +1,115 @@
+package code_units
+
+import (
+	"hercules/src/lexer"
+	"hercules/src/similarity_compute"
+	"hercules/src/syntax_tree"
+	"regexp"
+	"strings"
+)
+
+const MIN_UNIT_TOKENS = 40 // shorter functions like getters are in every repo
+const UNIT_WINNOWING_K = 12
+const UNIT_WINNOWING_WINDOW = 8 // smaller than for files, as functions are short
+
+const ANONYMOUS_UNIT_NAME = "<anonymous>"
+
+// Unit is a function or method of a file
+type Unit struct {
+	Path      string
+	Name      string
+	StartLine int // 1-based
+	EndLine   int
+	Tokens    int // number of tokens
+
+	fingerprints map[uint64]bool // winnowing fingerprints of the normalized tokens, so renaming doesn't change them
+}
+
+// Lines is the number of lines of the unit
+func (unit Unit) Lines() int {
+	return unit.EndLine - unit.StartLine + 1
+}
+
+// a call-like name in a function header, e.g. sum in `static int sum(int[] numbers) {`
+var UNIT_NAME_CALL = regexp.MustCompile(`([A-Za-z_$][\w$]*)\s*(?:<[^<>()]*>)?\s*\(`)
+
+// an assigned name in a function header, e.g. sum in `const sum = (numbers) => {`
+var UNIT_NAME_ASSIGNMENT = regexp.MustCompile(`([A-Za-z_$][\w$]*)\s*[:=]`)
+
+// Extract returns the functions of the file with at least MIN_UNIT_TOKENS tokens. Files the syntax
+// tree can parse are split at its function nodes, not going into nested functions, and other files
+// by a heuristic for function headers.
+func Extract(text string, path string) []Unit {
+	lines := strings.Split(text, "\n")
+	var ranges [][2]int
+	if syntax_tree.IsSupported(path) {
+		if root, err := syntax_tree.Parse(text, path); err == nil {
+			ranges = functionRanges(root)
+		}
+	} else {
+		ranges = heuristicFunctionRanges(lines)
+	}
+
+	language := lexer.LanguageFromPath(path)
+	if language == nil {
+		language = lexer.GENERIC
+	}
+	var units []Unit
+	for _, lineRange := range ranges {
+		startLine, endLine := lineRange[0], lineRange[1]
+		if startLine < 1 || endLine > len(lines) || endLine < startLine {
+			continue
+		}
+		unitText := strings.Join(lines[startLine-1:endLine], "\n")
+		normalizedText := lexer.Normalize(language, unitText)
+		if len(normalizedText.Tokens) < MIN_UNIT_TOKENS {
+			continue
+		}
+		fingerprints := make(map[uint64]bool)
+		for _, fingerprint := range similarity_compute.Winnow(normalizedText.Text, UNIT_WINNOWING_K, UNIT_WINNOWING_WINDOW) {
+			fingerprints[fingerprint.Hash] = true
+		}
+		units = append(units, Unit{
+			Path:         path,
+			Name:         unitName(lines, startLine, endLine),
+			StartLine:    startLine,
+			EndLine:      endLine,
+			Tokens:       len(normalizedText.Tokens),
+			fingerprints: fingerprints,
+		})
+	}
+	return units
+}
+
+// functionRanges are the line ranges of the outermost function nodes
+func functionRanges(node *syntax_tree.Node) [][2]int {
+	if node.Category() == syntax_tree.CATEGORY_FUNCTION {
+		return [][2]int{{node.StartLine, node.EndLine}}
+	}
+	var ranges [][2]int
+	for _, child := range node.Children {
+		ranges = append(ranges, functionRanges(child)...)
+	}
+	return ranges
+}
+
+// unitName is the first name in the header of the function that isn't a keyword, up to its opening
+// bracket, e.g. Name for `func (receiver *Type) Name() {`
+func unitName(lines []string, startLine int, endLine int) string {
+	var header strings.Builder
+	for line := startLine; line <= endLine; line++ {
+		header.WriteString(lines[line-1])
+		header.WriteString(" ")
+		if strings.ContainsAny(lines[line-1], "{:") {
+			break
+		}
+	}
+	for _, pattern := range []*regexp.Regexp{UNIT_NAME_CALL, UNIT_NAME_ASSIGNMENT} {
+		for _, match := range pattern.FindAllStringSubmatch(header.String(), -1) {
+			if !lexer.GENERIC.Keywords[match[1]] {
+				return match[1]
+			}
+		}
+	}
+	return ANONYMOUS_UNIT_NAME
+}
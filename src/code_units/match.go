@@ -0,0 +1,77 @@
+package code_units
+
+import (
+	"hercules/src/util"
+	"sort"
+)
+
+const UNIT_SIMILARITY_THRESHOLD = 0.7
+
+// Match is a function of the first set of units and the most similar function of the second
+type Match struct {
+	Unit1      Unit
+	Unit2      Unit
+	Similarity float64 // Dice coefficient of the fingerprints
+}
+
+// MatchUnits matches every unit of units1 with the most similar unit of units2 in a file of the same
+// language, across all files, if their similarity is above UNIT_SIMILARITY_THRESHOLD. Several units of
+// units1 can match the same unit of units2, as a function can be copied more than once.
+func MatchUnits(units1 []Unit, units2 []Unit) []Match {
+	// the units of units2 with every fingerprint, so only units sharing one are compared
+	unitsByFingerprint := make(map[uint64][]int)
+	for j, unit2 := range units2 {
+		for fingerprint := range unit2.fingerprints {
+			unitsByFingerprint[fingerprint] = append(unitsByFingerprint[fingerprint], j)
+		}
+	}
+
+	var matches []Match
+	for _, unit1 := range units1 {
+		shared := make(map[int]int)
+		for fingerprint := range unit1.fingerprints {
+			for _, j := range unitsByFingerprint[fingerprint] {
+				shared[j]++
+			}
+		}
+		bestIndex := -1
+		bestSimilarity := 0.0
+		for j, sharedFingerprints := range shared {
+			if !util.IsExtensionSame(unit1.Path, units2[j].Path) {
+				continue
+			}
+			similarity := 2 * float64(sharedFingerprints) / float64(len(unit1.fingerprints)+len(units2[j].fingerprints))
+			// ties go to the first unit, so the matches don't depend on the map order
+			if similarity > bestSimilarity || (similarity == bestSimilarity && j < bestIndex) {
+				bestIndex = j
+				bestSimilarity = similarity
+			}
+		}
+		if bestIndex >= 0 && bestSimilarity > UNIT_SIMILARITY_THRESHOLD {
+			matches = append(matches, Match{Unit1: unit1, Unit2: units2[bestIndex], Similarity: bestSimilarity})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Unit1.Path != matches[j].Unit1.Path {
+			return matches[i].Unit1.Path < matches[j].Unit1.Path
+		}
+		return matches[i].Unit1.StartLine < matches[j].Unit1.StartLine
+	})
+	return matches
+}
+
+// Coverage is the share of the lines of units that are in a unit matched as Unit1
+func Coverage(units []Unit, matches []Match) float64 {
+	totalLines := 0
+	for _, unit := range units {
+		totalLines += unit.Lines()
+	}
+	if totalLines == 0 {
+		return 0
+	}
+	matchedLines := 0
+	for _, match := range matches {
+		matchedLines += match.Unit1.Lines()
+	}
+	return float64(matchedLines) / float64(totalLines)
+}
@@ -0,0 +1,218 @@
+package corpus_index
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"hercules/src/minhash"
+	"os"
+	"sort"
+)
+
+const INDEX_VERSION = 1
+const MIN_SHARED_KEYWORDS = 2 // of the searched keywords a file must have, or all of them if fewer
+
+// IndexedFile is a code file of a repo on disk, as indexed
+type IndexedFile struct {
+	Repo      string   // absolute directory of the repo
+	Hash      string   // of the content, so a file is only indexed again when it changed
+	Terms     []string // distinct terms, to remove the file from the inverted index
+	Signature minhash.Signature
+}
+
+// Index is an inverted index of the terms of the code files of repos on disk, e.g. earlier submissions
+// and known solutions, with the MinHash signatures of the files, so the files a submission's file was
+// copied from can be found without searching GitHub. Repos can be added and removed.
+type Index struct {
+	Version  int
+	Bands    int
+	Rows     int
+	Files    map[string]*IndexedFile    // by absolute path
+	Postings map[string]map[string]bool // the paths of the files with every term
+
+	lsh *minhash.Index // of the signatures, built when first searched after a change
+}
+
+func New(bands int, rows int) *Index {
+	return &Index{
+		Version:  INDEX_VERSION,
+		Bands:    bands,
+		Rows:     rows,
+		Files:    make(map[string]*IndexedFile),
+		Postings: make(map[string]map[string]bool),
+	}
+}
+
+// Hash is the content hash the index keeps of a file
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsIndexed is true if the file at path is indexed with this content hash
+func (index *Index) IsIndexed(path string, hash string) bool {
+	file, ok := index.Files[path]
+	return ok && file.Hash == hash
+}
+
+// AddFile indexes the file at path of repo, replacing the file if it was indexed before
+func (index *Index) AddFile(repo string, path string, hash string, terms []string, signature minhash.Signature) {
+	index.RemoveFile(path)
+	distinctTerms := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if index.Postings[term] == nil {
+			index.Postings[term] = make(map[string]bool)
+		}
+		if !index.Postings[term][path] {
+			index.Postings[term][path] = true
+			distinctTerms = append(distinctTerms, term)
+		}
+	}
+	index.Files[path] = &IndexedFile{Repo: repo, Hash: hash, Terms: distinctTerms, Signature: signature}
+	index.lsh = nil
+}
+
+// RemoveFile removes the file at path from the index, if it is indexed
+func (index *Index) RemoveFile(path string) {
+	file, ok := index.Files[path]
+	if !ok {
+		return
+	}
+	for _, term := range file.Terms {
+		delete(index.Postings[term], path)
+		if len(index.Postings[term]) == 0 {
+			delete(index.Postings, term)
+		}
+	}
+	delete(index.Files, path)
+	index.lsh = nil
+}
+
+// RepoFiles are the indexed paths of the files of repo, in order
+func (index *Index) RepoFiles(repo string) []string {
+	var paths []string
+	for path, file := range index.Files {
+		if file.Repo == repo {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Repos are the indexed repos, in order
+func (index *Index) Repos() []string {
+	found := make(map[string]bool)
+	for _, file := range index.Files {
+		found[file.Repo] = true
+	}
+	repos := make([]string, 0, len(found))
+	for repo := range found {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// Search returns up to limit paths of the files with the most of the keywords, at least
+// MIN_SHARED_KEYWORDS of them, the files with the same number in order of path
+func (index *Index) Search(keywords []string, limit int) []string {
+	shared := make(map[string]int)
+	for _, keyword := range keywords {
+		for path := range index.Postings[keyword] {
+			shared[path]++
+		}
+	}
+	minShared := MIN_SHARED_KEYWORDS
+	if len(keywords) < minShared {
+		minShared = len(keywords)
+	}
+	var paths []string
+	for path, count := range shared {
+		if count >= minShared {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if shared[paths[i]] != shared[paths[j]] {
+			return shared[paths[i]] > shared[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	if len(paths) > limit {
+		paths = paths[:limit]
+	}
+	return paths
+}
+
+// Similar returns up to limit paths of the files whose signatures share a band with signature, the most
+// similar first
+func (index *Index) Similar(signature minhash.Signature, limit int) []string {
+	if index.lsh == nil {
+		index.lsh = minhash.NewIndex(index.Bands, index.Rows)
+		for path, file := range index.Files {
+			index.lsh.Add(path, file.Signature)
+		}
+	}
+	paths := index.lsh.Candidates(signature)
+	similarities := make(map[string]float64, len(paths))
+	for _, path := range paths {
+		similarities[path] = minhash.Similarity(signature, index.Files[path].Signature)
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return similarities[paths[i]] > similarities[paths[j]]
+	})
+	if len(paths) > limit {
+		paths = paths[:limit]
+	}
+	return paths
+}
+
+// Save writes the index to path, gzipped gob
+func (index *Index) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	if err := gob.NewEncoder(gzipWriter).Encode(index); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Load reads an index written by Save
+func Load(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a corpus index: %w", path, err)
+	}
+	var index Index
+	if err := gob.NewDecoder(gzipReader).Decode(&index); err != nil {
+		return nil, fmt.Errorf("%s is not a corpus index: %w", path, err)
+	}
+	if index.Version != INDEX_VERSION {
+		return nil, fmt.Errorf("%s is a version %d corpus index, expected version %d", path, index.Version, INDEX_VERSION)
+	}
+	// gob leaves empty maps out
+	if index.Files == nil {
+		index.Files = make(map[string]*IndexedFile)
+	}
+	if index.Postings == nil {
+		index.Postings = make(map[string]map[string]bool)
+	}
+	return &index, nil
+}
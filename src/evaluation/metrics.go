@@ -0,0 +1,151 @@
+package evaluation
+
+import (
+	"math"
+	"sort"
+)
+
+// Sample is the score of a metric for a labeled pair
+type Sample struct {
+	Score       float64
+	Label       bool   // true if the pair is plagiarised
+	Obfuscation string // of the copy, if known
+}
+
+// ThresholdReport is how well a metric does when pairs scoring above the threshold are called copied
+type ThresholdReport struct {
+	Threshold      float64 `json:"threshold"`
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	TrueNegatives  int     `json:"true_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+	F1             float64 `json:"f1"`
+}
+
+type ROCPoint struct {
+	Threshold         float64 `json:"threshold"`
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+	TruePositiveRate  float64 `json:"true_positive_rate"`
+}
+
+type MetricReport struct {
+	Metric               string             `json:"metric"`
+	AUC                  float64            `json:"auc"`
+	DefaultThreshold     *ThresholdReport   `json:"default_threshold,omitempty"` // the threshold hercules uses, if the metric has one
+	RecommendedThreshold ThresholdReport    `json:"recommended_threshold"`       // the threshold with the highest F1
+	RecallByObfuscation  map[string]float64 `json:"recall_by_obfuscation,omitempty"`
+	ROC                  []ROCPoint         `json:"roc"`
+}
+
+// EvaluateMetric computes the ROC curve of the samples, its area, and the precision, recall and F1
+// at the default threshold, if it isn't negative, and at the threshold with the highest F1
+func EvaluateMetric(metric string, samples []Sample, defaultThreshold float64) MetricReport {
+	for i := range samples {
+		if math.IsNaN(samples[i].Score) {
+			samples[i].Score = 0
+		}
+	}
+	metricReport := MetricReport{Metric: metric}
+	thresholds := candidateThresholds(samples)
+
+	metricReport.RecommendedThreshold = evaluateThreshold(samples, thresholds[0])
+	for _, threshold := range thresholds {
+		thresholdReport := evaluateThreshold(samples, threshold)
+		if thresholdReport.F1 > metricReport.RecommendedThreshold.F1 {
+			metricReport.RecommendedThreshold = thresholdReport
+		}
+		metricReport.ROC = append(metricReport.ROC, ROCPoint{
+			Threshold:         threshold,
+			FalsePositiveRate: ratio(thresholdReport.FalsePositives, thresholdReport.FalsePositives+thresholdReport.TrueNegatives),
+			TruePositiveRate:  thresholdReport.Recall,
+		})
+	}
+	// the trapezoids under the curve, whose points go from the highest threshold to the lowest
+	for i := 1; i < len(metricReport.ROC); i++ {
+		previous, point := metricReport.ROC[i-1], metricReport.ROC[i]
+		metricReport.AUC += (point.FalsePositiveRate - previous.FalsePositiveRate) * (point.TruePositiveRate + previous.TruePositiveRate) / 2
+	}
+
+	if defaultThreshold >= 0 {
+		thresholdReport := evaluateThreshold(samples, defaultThreshold)
+		metricReport.DefaultThreshold = &thresholdReport
+	}
+	metricReport.RecallByObfuscation = recallByObfuscation(samples, metricReport.RecommendedThreshold.Threshold)
+	return metricReport
+}
+
+// candidateThresholds are above the highest score, between every two successive distinct scores, and
+// below the lowest, descending, so every way of splitting the samples by score is tried once
+func candidateThresholds(samples []Sample) []float64 {
+	scores := make([]float64, 0, len(samples))
+	for _, sample := range samples {
+		scores = append(scores, sample.Score)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(scores)))
+
+	if len(scores) == 0 {
+		return []float64{0}
+	}
+	thresholds := []float64{scores[0] + 1}
+	for i := 1; i < len(scores); i++ {
+		if scores[i] != scores[i-1] {
+			thresholds = append(thresholds, (scores[i]+scores[i-1])/2)
+		}
+	}
+	return append(thresholds, scores[len(scores)-1]-1)
+}
+
+func evaluateThreshold(samples []Sample, threshold float64) ThresholdReport {
+	thresholdReport := ThresholdReport{Threshold: threshold}
+	for _, sample := range samples {
+		predicted := sample.Score > threshold
+		switch {
+		case predicted && sample.Label:
+			thresholdReport.TruePositives++
+		case predicted && !sample.Label:
+			thresholdReport.FalsePositives++
+		case !predicted && sample.Label:
+			thresholdReport.FalseNegatives++
+		default:
+			thresholdReport.TrueNegatives++
+		}
+	}
+	thresholdReport.Precision = ratio(thresholdReport.TruePositives, thresholdReport.TruePositives+thresholdReport.FalsePositives)
+	thresholdReport.Recall = ratio(thresholdReport.TruePositives, thresholdReport.TruePositives+thresholdReport.FalseNegatives)
+	if thresholdReport.Precision+thresholdReport.Recall > 0 {
+		thresholdReport.F1 = 2 * thresholdReport.Precision * thresholdReport.Recall / (thresholdReport.Precision + thresholdReport.Recall)
+	}
+	return thresholdReport
+}
+
+// recallByObfuscation is the share of the copies of every obfuscation found at the threshold
+func recallByObfuscation(samples []Sample, threshold float64) map[string]float64 {
+	found := make(map[string]int)
+	total := make(map[string]int)
+	for _, sample := range samples {
+		if !sample.Label || sample.Obfuscation == "" {
+			continue
+		}
+		total[sample.Obfuscation]++
+		if sample.Score > threshold {
+			found[sample.Obfuscation]++
+		}
+	}
+	if len(total) == 0 {
+		return nil
+	}
+	recalls := make(map[string]float64, len(total))
+	for obfuscation, count := range total {
+		recalls[obfuscation] = ratio(found[obfuscation], count)
+	}
+	return recalls
+}
+
+func ratio(numerator int, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
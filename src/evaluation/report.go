@@ -0,0 +1,91 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const LEVEL_FILE = "file"
+const LEVEL_CROSS_LANGUAGE = "cross-language file"
+const LEVEL_REPO = "repo"
+
+// Report is the evaluation of the metrics on a labeled dataset, for the file pairs and the repo pairs in it
+type Report struct {
+	Dataset string        `json:"dataset"`
+	Levels  []LevelReport `json:"levels"`
+}
+
+type LevelReport struct {
+	Level     string         `json:"level"`
+	Pairs     int            `json:"pairs"`
+	Positives int            `json:"positives"`
+	Metrics   []MetricReport `json:"metrics"`
+}
+
+func (report *Report) SaveJSON(path string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Markdown is the report as Markdown tables, without the ROC curves
+func (report *Report) Markdown() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# Evaluation of %s\n", report.Dataset)
+	for _, levelReport := range report.Levels {
+		fmt.Fprintf(&builder, "\n## %s pairs\n\n", strings.ToUpper(levelReport.Level[:1])+levelReport.Level[1:])
+		fmt.Fprintf(&builder, "%d pairs, %d copied.\n\n", levelReport.Pairs, levelReport.Positives)
+
+		builder.WriteString("| Metric | AUC | Default Threshold | Precision | Recall | F1 | Recommended Threshold | Precision | Recall | F1 |\n")
+		builder.WriteString("|---|---|---|---|---|---|---|---|---|---|\n")
+		for _, metricReport := range levelReport.Metrics {
+			defaultColumns := "- | - | - | -"
+			if metricReport.DefaultThreshold != nil {
+				defaultColumns = thresholdColumns(*metricReport.DefaultThreshold)
+			}
+			fmt.Fprintf(&builder, "| %s | %.4f | %s | %s |\n",
+				metricReport.Metric, metricReport.AUC, defaultColumns, thresholdColumns(metricReport.RecommendedThreshold))
+		}
+
+		obfuscations := levelObfuscations(levelReport)
+		if len(obfuscations) == 0 {
+			continue
+		}
+		builder.WriteString("\nRecall by obfuscation at the recommended thresholds:\n\n")
+		builder.WriteString("| Metric | " + strings.Join(obfuscations, " | ") + " |\n")
+		builder.WriteString("|---|" + strings.Repeat("---|", len(obfuscations)) + "\n")
+		for _, metricReport := range levelReport.Metrics {
+			recalls := make([]string, len(obfuscations))
+			for i, obfuscation := range obfuscations {
+				recalls[i] = fmt.Sprintf("%.4f", metricReport.RecallByObfuscation[obfuscation])
+			}
+			fmt.Fprintf(&builder, "| %s | %s |\n", metricReport.Metric, strings.Join(recalls, " | "))
+		}
+	}
+	return builder.String()
+}
+
+func thresholdColumns(thresholdReport ThresholdReport) string {
+	return fmt.Sprintf("%.4f | %.4f | %.4f | %.4f",
+		thresholdReport.Threshold, thresholdReport.Precision, thresholdReport.Recall, thresholdReport.F1)
+}
+
+func levelObfuscations(levelReport LevelReport) []string {
+	var obfuscations []string
+	seen := make(map[string]bool)
+	for _, metricReport := range levelReport.Metrics {
+		for obfuscation := range metricReport.RecallByObfuscation {
+			if !seen[obfuscation] {
+				seen[obfuscation] = true
+				obfuscations = append(obfuscations, obfuscation)
+			}
+		}
+	}
+	sort.Strings(obfuscations)
+	return obfuscations
+}
@@ -0,0 +1,242 @@
+package git_history
+
+import (
+	"fmt"
+	"hercules/src/util"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const BULK_PASTE_SHARE = 0.5     // share of the final code lines added by one commit
+const MIN_BULK_PASTE_LINES = 100 // tiny submissions are always written in one go
+const MAX_COMMITS_TO_ANALYSE = 1000
+
+const (
+	FINDING_BULK_PASTE       = "bulk paste"
+	FINDING_FOREIGN_AUTHOR   = "foreign author"
+	FINDING_PREDATES_RELEASE = "predates release"
+	FINDING_SHARED_ROOT      = "shared root commit"
+)
+
+type AnomalyOptions struct {
+	StudentEmails []string  // if empty, the most frequent author is assumed to be the student
+	ReleaseDate   time.Time // zero means unknown
+}
+
+type HistoryFinding struct {
+	Kind        string
+	Commit      string
+	Description string
+}
+
+// DetectAnomalies looks for signs of copied work in the history of a submission: commits adding
+// most of the final code at once, commits by other authors and commits dated before the release.
+func (history *History) DetectAnomalies(options AnomalyOptions) ([]HistoryFinding, error) {
+	commits, err := history.commitsOldestFirst()
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	var findings []HistoryFinding
+
+	// the commits are in committer date order, in which an amended date or a wrong clock can put one after the head
+	head, err := history.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	finalCodeLines, err := history.countCodeLines(head)
+	if err != nil {
+		return nil, err
+	}
+	if finalCodeLines >= MIN_BULK_PASTE_LINES {
+		for _, commit := range commits {
+			if commit.NumParents() > 1 {
+				continue // merges repeat what their parents added
+			}
+			addedCodeLines, err := countAddedCodeLines(commit)
+			if err != nil {
+				continue
+			}
+			share := float64(addedCodeLines) / float64(finalCodeLines)
+			if share >= BULK_PASTE_SHARE {
+				findings = append(findings, HistoryFinding{
+					Kind:   FINDING_BULK_PASTE,
+					Commit: commit.Hash.String(),
+					Description: fmt.Sprintf("adds %d lines, %.0f%% of the final %d lines of code",
+						addedCodeLines, share*100, finalCodeLines),
+				})
+			}
+		}
+	}
+
+	studentEmails := make(map[string]bool)
+	assumedStudent := ""
+	for _, email := range options.StudentEmails {
+		studentEmails[strings.ToLower(email)] = true
+	}
+	if len(studentEmails) == 0 {
+		assumedStudent = mostFrequentAuthor(commits)
+		studentEmails[assumedStudent] = true
+	}
+	foreignCommits := make(map[string][]*object.Commit)
+	var foreignEmails []string
+	for _, commit := range commits {
+		email := strings.ToLower(commit.Author.Email)
+		if studentEmails[email] {
+			continue
+		}
+		if _, ok := foreignCommits[email]; !ok {
+			foreignEmails = append(foreignEmails, email)
+		}
+		foreignCommits[email] = append(foreignCommits[email], commit)
+	}
+	for _, email := range foreignEmails {
+		description := fmt.Sprintf("%d commit(s) by %s", len(foreignCommits[email]), email)
+		if assumedStudent != "" {
+			description += fmt.Sprintf(" (student assumed to be %s, the most frequent author)", assumedStudent)
+		}
+		findings = append(findings, HistoryFinding{
+			Kind:        FINDING_FOREIGN_AUTHOR,
+			Commit:      foreignCommits[email][0].Hash.String(),
+			Description: description,
+		})
+	}
+
+	if !options.ReleaseDate.IsZero() {
+		for _, commit := range commits {
+			if commit.Author.When.Before(options.ReleaseDate) || commit.Committer.When.Before(options.ReleaseDate) {
+				findings = append(findings, HistoryFinding{
+					Kind:   FINDING_PREDATES_RELEASE,
+					Commit: commit.Hash.String(),
+					Description: fmt.Sprintf("dated %s, before the release on %s",
+						commit.Author.When.Format(time.DateOnly), options.ReleaseDate.Format(time.DateOnly)),
+				})
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// RootCommits returns the hashes of the commits without parents, oldest first. Unlike the anomaly
+// detection, it walks the whole history, as the roots are the oldest commits of a long history.
+func (history *History) RootCommits() ([]string, error) {
+	commitIter, err := history.Repository.Log(&git.LogOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer commitIter.Close()
+
+	var roots []*object.Commit
+	err = commitIter.ForEach(func(commit *object.Commit) error {
+		if commit.NumParents() == 0 {
+			roots = append(roots, commit)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(roots, func(i, j int) bool {
+		return roots[i].Committer.When.Before(roots[j].Committer.When)
+	})
+	hashes := make([]string, len(roots))
+	for i, root := range roots {
+		hashes[i] = root.Hash.String()
+	}
+	return hashes, nil
+}
+
+// SharedRootCommit returns a root commit both histories have, or "" if there is none
+func (history *History) SharedRootCommit(other *History) string {
+	roots, err := history.RootCommits()
+	if err != nil {
+		return ""
+	}
+	otherRoots, err := other.RootCommits()
+	if err != nil {
+		return ""
+	}
+	for _, root := range roots {
+		if util.Contains(otherRoots, root) {
+			return root
+		}
+	}
+	return ""
+}
+
+func (history *History) commitsOldestFirst() ([]*object.Commit, error) {
+	commitIter, err := history.Repository.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, err
+	}
+	defer commitIter.Close()
+
+	var commits []*object.Commit
+	for len(commits) < MAX_COMMITS_TO_ANALYSE {
+		commit, err := commitIter.Next()
+		if err != nil {
+			break
+		}
+		commits = append(commits, commit)
+	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Committer.When.Before(commits[j].Committer.When)
+	})
+	return commits, nil
+}
+
+func (history *History) countCodeLines(commit *object.Commit) (int, error) {
+	fileIter, err := commit.Files()
+	if err != nil {
+		return 0, err
+	}
+	defer fileIter.Close()
+
+	count := 0
+	err = fileIter.ForEach(func(file *object.File) error {
+		if !util.IsCodeFile(file.Name) {
+			return nil
+		}
+		lines, err := file.Lines()
+		if err == nil {
+			count += len(lines)
+		}
+		return nil
+	})
+	return count, err
+}
+
+func countAddedCodeLines(commit *object.Commit) (int, error) {
+	stats, err := commit.Stats()
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, stat := range stats {
+		if util.IsCodeFile(stat.Name) {
+			added += stat.Addition
+		}
+	}
+	return added, nil
+}
+
+func mostFrequentAuthor(commits []*object.Commit) string {
+	counts := make(map[string]int)
+	mostFrequent := ""
+	for _, commit := range commits {
+		email := strings.ToLower(commit.Author.Email)
+		counts[email]++
+		if counts[email] > counts[mostFrequent] {
+			mostFrequent = email
+		}
+	}
+	return mostFrequent
+}
@@ -0,0 +1,53 @@
+package git_history
+
+import (
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// History is a git repository together with the root of its worktree,
+// so that absolute file paths can be mapped to paths in the repository.
+type History struct {
+	Repository *git.Repository
+	Root       string
+}
+
+// Open opens the git repository containing dir, searching parent directories
+func Open(dir string) (*History, error) {
+	repository, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(worktree.Filesystem.Root())
+	if err != nil {
+		return nil, err
+	}
+	return &History{Repository: repository, Root: root}, nil
+}
+
+// RelativePath converts an absolute file path into a slash separated path in the repository
+func (history *History) RelativePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(history.Root, absPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(relPath), nil
+}
+
+func (history *History) HeadCommit() (*object.Commit, error) {
+	head, err := history.Repository.Head()
+	if err != nil {
+		return nil, err
+	}
+	return history.Repository.CommitObject(head.Hash())
+}
@@ -0,0 +1,169 @@
+package git_history
+
+import (
+	"hercules/src/util"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const MAX_COMMITS_TO_WALK = 300
+const REGION_PRESENCE_THRESHOLD = 0.5 // share of the region's lines a version of the file must contain
+const COPY_DIRECTION_MARGIN = 24 * time.Hour
+
+const (
+	SOURCE_PREDATES_SUBMISSION = "source predates submission"
+	SUBMISSION_PREDATES_SOURCE = "submission predates source"
+	INCONCLUSIVE               = "inconclusive"
+)
+
+// RegionProvenance is when a region of a file first appeared in a repository's history
+type RegionProvenance struct {
+	Found           bool      // whether a commit containing the region was found
+	FirstSeen       time.Time // committer time of the first commit whose version of the file contains the region
+	FirstSeenCommit string
+	BlameEarliest   time.Time // earliest and latest time the region's lines were last changed at, from blame
+	BlameLatest     time.Time
+}
+
+// FindRegionProvenance finds when the region text[startIndex:endIndex] of the file at path first
+// appeared, by walking the file's log from the oldest commit and by blaming the region's lines at HEAD.
+func (history *History) FindRegionProvenance(path string, text string, startIndex int, endIndex int) (RegionProvenance, error) {
+	var provenance RegionProvenance
+
+	relPath, err := history.RelativePath(path)
+	if err != nil {
+		return provenance, err
+	}
+
+	startIndex = clamp(startIndex, 0, len(text))
+	endIndex = clamp(endIndex, startIndex, len(text))
+	startLine := strings.Count(text[:startIndex], "\n")
+	endLine := startLine + strings.Count(text[startIndex:endIndex], "\n")
+	regionLines := significantLines(text[startIndex:endIndex])
+
+	commits, err := history.fileCommitsOldestFirst(relPath)
+	if err != nil {
+		return provenance, err
+	}
+
+	if len(regionLines) > 0 {
+		for i, commit := range commits {
+			if i >= MAX_COMMITS_TO_WALK {
+				break
+			}
+			file, err := commit.File(relPath)
+			if err != nil {
+				continue // file didn't exist under this path in that commit
+			}
+			contents, err := file.Contents()
+			if err != nil {
+				continue
+			}
+			if regionPresence(regionLines, contents) >= REGION_PRESENCE_THRESHOLD {
+				provenance.Found = true
+				provenance.FirstSeen = commit.Committer.When
+				provenance.FirstSeenCommit = commit.Hash.String()
+				break
+			}
+		}
+	}
+
+	headCommit, err := history.HeadCommit()
+	if err != nil {
+		return provenance, nil
+	}
+	blameResult, err := git.Blame(headCommit, relPath)
+	if err != nil {
+		return provenance, nil
+	}
+	for lineIndex := startLine; lineIndex <= endLine && lineIndex < len(blameResult.Lines); lineIndex++ {
+		date := blameResult.Lines[lineIndex].Date
+		if provenance.BlameEarliest.IsZero() || date.Before(provenance.BlameEarliest) {
+			provenance.BlameEarliest = date
+		}
+		if date.After(provenance.BlameLatest) {
+			provenance.BlameLatest = date
+		}
+	}
+	return provenance, nil
+}
+
+// LabelCopyDirection compares when the matched region appeared on both sides
+func LabelCopyDirection(submission RegionProvenance, source RegionProvenance) string {
+	var submissionTime, sourceTime time.Time
+	if submission.Found && source.Found {
+		submissionTime, sourceTime = submission.FirstSeen, source.FirstSeen
+	} else if !submission.BlameEarliest.IsZero() && !source.BlameEarliest.IsZero() {
+		submissionTime, sourceTime = submission.BlameEarliest, source.BlameEarliest
+	} else {
+		return INCONCLUSIVE
+	}
+
+	if sourceTime.Add(COPY_DIRECTION_MARGIN).Before(submissionTime) {
+		return SOURCE_PREDATES_SUBMISSION
+	}
+	if submissionTime.Add(COPY_DIRECTION_MARGIN).Before(sourceTime) {
+		return SUBMISSION_PREDATES_SOURCE
+	}
+	return INCONCLUSIVE
+}
+
+func (history *History) fileCommitsOldestFirst(relPath string) ([]*object.Commit, error) {
+	commitIter, err := history.Repository.Log(&git.LogOptions{FileName: &relPath})
+	if err != nil {
+		return nil, err
+	}
+	defer commitIter.Close()
+
+	var commits []*object.Commit
+	err = commitIter.ForEach(func(commit *object.Commit) error {
+		commits = append(commits, commit)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Committer.When.Before(commits[j].Committer.When)
+	})
+	return commits, nil
+}
+
+func significantLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if util.IsSignificantLine(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func regionPresence(regionLines []string, contents string) float64 {
+	fileLines := make(map[string]bool)
+	for _, line := range strings.Split(contents, "\n") {
+		fileLines[strings.TrimSpace(line)] = true
+	}
+	present := 0
+	for _, line := range regionLines {
+		if fileLines[line] {
+			present++
+		}
+	}
+	return float64(present) / float64(len(regionLines))
+}
+
+func clamp(value int, low int, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
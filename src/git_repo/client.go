@@ -0,0 +1,161 @@
+package git_repo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+const DEFAULT_API_BASE_URL = "https://api.github.com"
+const DEFAULT_WEB_BASE_URL = "https://github.com"
+
+// GitHubConfig describes where the GitHub layer talks to and how.
+// Point both base urls at a GitHub Enterprise Server instance
+// (e.g. https://ghes.example.com/api/v3 and https://ghes.example.com)
+// to run Hercules against it.
+type GitHubConfig struct {
+	APIBaseURL         string
+	WebBaseURL         string
+	ProxyURL           string // empty means use HTTPS_PROXY/HTTP_PROXY from the environment
+	CABundlePath       string // PEM file appended to the system cert pool
+	InsecureSkipVerify bool
+}
+
+var gitHubConfig = DefaultGitHubConfig()
+var httpClient = &http.Client{}
+
+func DefaultGitHubConfig() GitHubConfig {
+	return GitHubConfig{
+		APIBaseURL: DEFAULT_API_BASE_URL,
+		WebBaseURL: DEFAULT_WEB_BASE_URL,
+	}
+}
+
+// GitHubConfigFromEnv reads the config from the environment (and .env),
+// falling back to github.com for anything that is not set.
+func GitHubConfigFromEnv() GitHubConfig {
+	config := DefaultGitHubConfig()
+	if apiBaseURL := os.Getenv("GITHUB_API_URL"); apiBaseURL != "" {
+		config.APIBaseURL = apiBaseURL
+	}
+	if webBaseURL := os.Getenv("GITHUB_WEB_URL"); webBaseURL != "" {
+		config.WebBaseURL = webBaseURL
+	}
+	config.ProxyURL = os.Getenv("GITHUB_PROXY")
+	config.CABundlePath = os.Getenv("GITHUB_CA_BUNDLE")
+	config.InsecureSkipVerify = os.Getenv("GITHUB_INSECURE_SKIP_VERIFY") == "true"
+	return config
+}
+
+// Configure builds an http client from config and installs it for the
+// GitHub API calls as well as for cloning.
+func Configure(config GitHubConfig) error {
+	if _, err := url.ParseRequestURI(config.APIBaseURL); err != nil {
+		return fmt.Errorf("invalid GitHub API url %q: %v", config.APIBaseURL, err)
+	}
+	if _, err := url.ParseRequestURI(config.WebBaseURL); err != nil {
+		return fmt.Errorf("invalid GitHub url %q: %v", config.WebBaseURL, err)
+	}
+	config.APIBaseURL = strings.TrimSuffix(config.APIBaseURL, "/")
+	config.WebBaseURL = strings.TrimSuffix(config.WebBaseURL, "/")
+
+	newClient, err := NewHTTPClient(config)
+	if err != nil {
+		return err
+	}
+	gitHubConfig = config
+	SetHTTPClient(newClient)
+	return nil
+}
+
+// NewHTTPClient creates an http client honouring the proxy and TLS settings of config.
+func NewHTTPClient(config GitHubConfig) (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url %q: %v", config.ProxyURL, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CABundlePath != "" {
+		pem, err := os.ReadFile(config.CABundlePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %v", err)
+		}
+		certPool, err := x509.SystemCertPool()
+		if err != nil {
+			certPool = x509.NewCertPool()
+		}
+		if !certPool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", config.CABundlePath)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// SetHTTPClient injects the client used by the whole GitHub layer,
+// e.g. one returned by httptest.Server.Client().
+func SetHTTPClient(newClient *http.Client) {
+	httpClient = newClient
+	client.InstallProtocol("https", githttp.NewClient(newClient))
+	client.InstallProtocol("http", githttp.NewClient(newClient))
+}
+
+// SetBaseURLs points the GitHub layer at other endpoints without touching the client.
+func SetBaseURLs(apiBaseURL string, webBaseURL string) {
+	gitHubConfig.APIBaseURL = strings.TrimSuffix(apiBaseURL, "/")
+	gitHubConfig.WebBaseURL = strings.TrimSuffix(webBaseURL, "/")
+}
+
+func GetGitHubConfig() GitHubConfig {
+	return gitHubConfig
+}
+
+// RepoURL returns the web url of a repository given its full name (owner/repo)
+func RepoURL(fullName string) string {
+	return gitHubConfig.WebBaseURL + "/" + fullName
+}
+
+func apiURL(format string, args ...any) string {
+	return gitHubConfig.APIBaseURL + fmt.Sprintf(format, args...)
+}
+
+func newAPIRequest(url string, accept string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+
+	// Add authorization header if token exists
+	token := os.Getenv("GITHUB_TOKEN")
+	if token != "" {
+		req.Header.Set("Authorization", "token "+token)
+	}
+	return req, nil
+}
+
+func cloneAuth() transport.AuthMethod {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" || gitHubConfig.WebBaseURL == DEFAULT_WEB_BASE_URL {
+		// public github.com repos don't need auth
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "hercules", Password: token}
+}
@@ -0,0 +1,88 @@
+package git_repo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type GitHubRepositoryRef struct {
+	FullName string `json:"full_name"`
+}
+
+type GitHubRepository struct {
+	FullName string `json:"full_name"`
+	Owner    struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Fork      bool                 `json:"fork"`
+	MirrorURL string               `json:"mirror_url"`
+	Parent    *GitHubRepositoryRef `json:"parent"`
+	Source    *GitHubRepositoryRef `json:"source"`
+}
+
+func GetRepository(fullName string) (GitHubRepository, error) {
+	var repository GitHubRepository
+
+	req, err := newAPIRequest(apiURL("/repos/%s", fullName), "application/vnd.github.v3+json")
+	if err != nil {
+		return repository, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return repository, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return repository, fmt.Errorf("error fetching repository %s: %s", fullName, resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&repository)
+	return repository, err
+}
+
+// NetworkRoot returns the full name of the root of the repository's fork network
+func (repository GitHubRepository) NetworkRoot() string {
+	if repository.Source != nil && repository.Source.FullName != "" {
+		return repository.Source.FullName
+	}
+	if repository.Parent != nil && repository.Parent.FullName != "" {
+		return repository.Parent.FullName
+	}
+	return repository.FullName
+}
+
+// UpstreamKey identifies the code the repository ultimately comes from,
+// so that mirrors and forks of one upstream share the same key.
+func (repository GitHubRepository) UpstreamKey() string {
+	if repository.MirrorURL != "" {
+		return normalizeMirrorURL(repository.MirrorURL)
+	}
+	return strings.ToLower(repository.NetworkRoot())
+}
+
+func normalizeMirrorURL(mirrorURL string) string {
+	parsedURL, err := url.Parse(mirrorURL)
+	if err != nil {
+		return strings.ToLower(mirrorURL)
+	}
+	path := strings.TrimSuffix(strings.Trim(parsedURL.Path, "/"), ".git")
+	webURL, err := url.Parse(gitHubConfig.WebBaseURL)
+	if err == nil && parsedURL.Host == webURL.Host {
+		// mirror of a repository on the same GitHub, so key it by owner/repo
+		return strings.ToLower(path)
+	}
+	return strings.ToLower(parsedURL.Host + "/" + path)
+}
+
+// GetOwnerFromRepoName returns the owner part of owner/repo
+func GetOwnerFromRepoName(repoName string) string {
+	owner, _, found := strings.Cut(repoName, "/")
+	if !found {
+		return ""
+	}
+	return owner
+}
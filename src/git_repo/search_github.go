@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
-	"os"
 	"time"
 )
 
@@ -28,20 +26,13 @@ func SearchGitHub(query string, numberOfQueries int) (GitHubSearchResult, error)
 	var result GitHubSearchResult
 
 	for i := 0; i < maxRetries; i++ {
-		url := fmt.Sprintf("https://api.github.com/search/code?q=%s&per_page=%d", query, numberOfQueries)
-		req, err := http.NewRequest("GET", url, nil)
+		url := apiURL("/search/code?q=%s&per_page=%d", query, numberOfQueries)
+		req, err := newAPIRequest(url, "application/vnd.github.v3+json")
 		if err != nil {
 			return result, err
 		}
-		req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-		token := os.Getenv("GITHUB_TOKEN")
-		if token != "" {
-			req.Header.Set("Authorization", "token "+token)
-		}
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		resp, err := httpClient.Do(req)
 		if err != nil {
 			return result, err
 		}
@@ -67,24 +58,16 @@ func SearchGitHub(query string, numberOfQueries int) (GitHubSearchResult, error)
 
 func FetchRawFileFromGitHub(item GitHubItem) (string, error) {
 	// Build the URL to fetch the raw file content
-	url := fmt.Sprintf("https://api.github.com/repos/%s/contents/%s", item.Repository.FullName, item.Path)
+	url := apiURL("/repos/%s/contents/%s", item.Repository.FullName, item.Path)
 
-	// Create an HTTP request
-	req, err := http.NewRequest("GET", url, nil)
+	// Create an HTTP request, with authorization header if token exists
+	req, err := newAPIRequest(url, "application/vnd.github.v3.raw")
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3.raw")
-
-	// Add authorization header if token exists
-	token := os.Getenv("GITHUB_TOKEN")
-	if token != "" {
-		req.Header.Set("Authorization", "token "+token)
-	}
 
-	// Create an HTTP client and perform the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Perform the request with the configured client
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -99,3 +82,38 @@ func FetchRawFileFromGitHub(item GitHubItem) (string, error) {
 	// Convert the data to a string
 	return string(data), nil
 }
+
+type GitHubCommitItem struct {
+	Sha        string `json:"sha"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+type GitHubCommitSearchResult struct {
+	TotalCount int                `json:"total_count"`
+	Items      []GitHubCommitItem `json:"items"`
+}
+
+// SearchCommitsByHash finds the public repositories containing the commit with the given hash
+func SearchCommitsByHash(hash string) (GitHubCommitSearchResult, error) {
+	var result GitHubCommitSearchResult
+
+	req, err := newAPIRequest(apiURL("/search/commits?q=hash:%s", hash), "application/vnd.github.v3+json")
+	if err != nil {
+		return result, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return result, fmt.Errorf("error searching commit %s: %s", hash, resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return result, err
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"log"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -15,18 +13,14 @@ import (
 func GitClone(url string, directory string) {
 	fmt.Println("Cloning repository " + url)
 	_, err := git.PlainClone(directory, false, &git.CloneOptions{
-		URL: url,
+		URL:  url,
+		Auth: cloneAuth(),
 	})
 	if err != nil {
 		log.Printf("Error cloning repository: %v", err)
 	}
-
-	// Remove the .git directory
-	gitDir := filepath.Join(directory, ".git")
-	err = os.RemoveAll(gitDir)
-	if err != nil {
-		log.Printf("Error removing .git directory: %v", err)
-	}
+	// the .git directory is kept for the history analysis,
+	// GetFilePaths skips it so it's never compared
 }
 
 func GetRepoNameFromUrl(repoUrl string) (string, error) {
@@ -63,10 +57,11 @@ func GetRepoNameFromUrl(repoUrl string) (string, error) {
 func IsValidGitHubURL(testURL string) bool {
 	parsedURL, err := url.Parse(testURL)
 
-	// Check for errors, ensure it's HTTP/HTTPS, and the host is github.com
-	if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" ||
+	// Check for errors, ensure it's HTTP/HTTPS, and the host is the configured GitHub host
+	webURL, webErr := url.Parse(gitHubConfig.WebBaseURL)
+	if err != nil || webErr != nil || parsedURL.Scheme == "" || parsedURL.Host == "" ||
 		(parsedURL.Scheme != "http" && parsedURL.Scheme != "https") ||
-		parsedURL.Host != "github.com" {
+		parsedURL.Host != webURL.Host {
 		return false
 	}
 
@@ -74,3 +69,27 @@ func IsValidGitHubURL(testURL string) bool {
 	parts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
 	return len(parts) >= 2
 }
+
+// GetRepoNameFromGitRemote returns owner/repo of the origin remote of a local
+// checkout, if it points at the configured GitHub host.
+func GetRepoNameFromGitRemote(directory string) (string, error) {
+	repository, err := git.PlainOpenWithOptions(directory, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return "", err
+	}
+	remote, err := repository.Remote("origin")
+	if err != nil {
+		return "", err
+	}
+	for _, remoteUrl := range remote.Config().URLs {
+		remoteUrl = strings.TrimSuffix(remoteUrl, ".git")
+		if strings.HasPrefix(remoteUrl, "git@") {
+			// git@host:owner/repo -> https://host/owner/repo
+			remoteUrl = "https://" + strings.Replace(strings.TrimPrefix(remoteUrl, "git@"), ":", "/", 1)
+		}
+		if IsValidGitHubURL(remoteUrl) {
+			return GetRepoNameFromUrl(remoteUrl)
+		}
+	}
+	return "", errors.New("origin is not a GitHub remote")
+}
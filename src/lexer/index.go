@@ -0,0 +1,266 @@
+package lexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TokenKind int
+
+const (
+	KEYWORD TokenKind = iota
+	IDENTIFIER
+	LITERAL
+	OPERATOR
+)
+
+const NORMALIZED_IDENTIFIER = "ID"
+const NORMALIZED_LITERAL = "LIT"
+
+type Token struct {
+	Kind   TokenKind
+	Text   string
+	Offset int // byte offset in the source
+	Line   int // 1-based
+	Column int // 1-based, in bytes
+}
+
+// Normalized is what the token looks like after renaming identifiers and changing literals
+// doesn't matter anymore: identifiers become ID, literals LIT, and the rest stays as is.
+func (token Token) Normalized() string {
+	switch token.Kind {
+	case IDENTIFIER:
+		return NORMALIZED_IDENTIFIER
+	case LITERAL:
+		return NORMALIZED_LITERAL
+	default:
+		return token.Text
+	}
+}
+
+// longest first, so that the longest operator is matched
+var OPERATORS = []string{
+	">>>=", "<<=", ">>=", ">>>", "...", "===", "!==", "**=", "//=", "&^=",
+	"->", "=>", "::", "&&", "||", "==", "!=", "<=", ">=", "++", "--", "+=", "-=", "*=", "/=", "%=",
+	"&=", "|=", "^=", "<<", ">>", ":=", "**", "//", "<-", "&^", "?.", "??",
+}
+
+// Comment is a comment the lexer skipped, with its delimiters
+type Comment struct {
+	Text   string
+	Offset int // byte offset in the source
+	Line   int // 1-based
+}
+
+type lexerState struct {
+	language *Language
+	text     string
+	offset   int
+	line     int
+	column   int
+	tokens   []Token
+	comments []Comment
+}
+
+// Lex splits text into tokens, dropping whitespace and comments
+func Lex(language *Language, text string) []Token {
+	tokens, _ := LexWithComments(language, text)
+	return tokens
+}
+
+// LexWithComments is Lex that also returns the comments it dropped
+func LexWithComments(language *Language, text string) ([]Token, []Comment) {
+	state := &lexerState{language: language, text: text, line: 1, column: 1}
+
+	for state.offset < len(text) {
+		rest := text[state.offset:]
+		char, size := utf8.DecodeRuneInString(rest)
+		start := state.offset
+
+		switch {
+		case unicode.IsSpace(char):
+			state.advance(utf8.RuneLen(char))
+
+		case state.skipComment(rest):
+
+		case state.lexString(rest, ""):
+
+		case isIdentifierStart(char):
+			length := identifierLength(rest)
+			word := rest[:length]
+			if state.isStringPrefix(word) && state.lexString(rest[length:], word) {
+				continue
+			}
+			kind := IDENTIFIER
+			if language.Keywords[word] {
+				kind = KEYWORD
+			}
+			state.emit(kind, length)
+
+		case isDigit(char) || (char == '.' && len(rest) > 1 && isDigit(rune(rest[1]))):
+			state.emit(LITERAL, numberLength(rest))
+
+		default:
+			state.emit(OPERATOR, operatorLength(rest))
+		}
+
+		// every rune is consumed by some case, a case that took nothing must not loop forever
+		if state.offset == start {
+			state.emit(OPERATOR, size)
+		}
+	}
+	return state.tokens, state.comments
+}
+
+func (state *lexerState) advance(length int) {
+	for _, char := range state.text[state.offset : state.offset+length] {
+		if char == '\n' {
+			state.line++
+			state.column = 1
+		} else {
+			state.column += utf8.RuneLen(char)
+		}
+	}
+	state.offset += length
+}
+
+func (state *lexerState) emit(kind TokenKind, length int) {
+	state.tokens = append(state.tokens, Token{
+		Kind:   kind,
+		Text:   state.text[state.offset : state.offset+length],
+		Offset: state.offset,
+		Line:   state.line,
+		Column: state.column,
+	})
+	state.advance(length)
+}
+
+func (state *lexerState) emitComment(length int) {
+	state.comments = append(state.comments, Comment{
+		Text:   state.text[state.offset : state.offset+length],
+		Offset: state.offset,
+		Line:   state.line,
+	})
+	state.advance(length)
+}
+
+func (state *lexerState) skipComment(rest string) bool {
+	for _, lineComment := range state.language.LineComments {
+		if strings.HasPrefix(rest, lineComment) {
+			length := strings.IndexByte(rest, '\n')
+			if length < 0 {
+				length = len(rest)
+			}
+			state.emitComment(length)
+			return true
+		}
+	}
+	for _, blockComment := range state.language.BlockComments {
+		if strings.HasPrefix(rest, blockComment[0]) {
+			end := strings.Index(rest[len(blockComment[0]):], blockComment[1])
+			length := len(rest)
+			if end >= 0 {
+				length = len(blockComment[0]) + end + len(blockComment[1])
+			}
+			state.emitComment(length)
+			return true
+		}
+	}
+	return false
+}
+
+// lexString emits a string literal starting at rest, including the prefix before it
+func (state *lexerState) lexString(rest string, prefix string) bool {
+	for _, quote := range state.language.RawStringQuotes {
+		if strings.HasPrefix(rest, quote) {
+			end := strings.Index(rest[len(quote):], quote)
+			length := len(rest)
+			if end >= 0 {
+				length = len(quote) + end + len(quote)
+			}
+			state.emit(LITERAL, len(prefix)+length)
+			return true
+		}
+	}
+	for _, quote := range state.language.StringQuotes {
+		if strings.HasPrefix(rest, quote) {
+			raw := strings.ContainsAny(prefix, "rR")
+			multiline := state.language.MultilineStrings && len(quote) == 3
+			length := stringLength(rest, quote, raw, multiline)
+			state.emit(LITERAL, len(prefix)+length)
+			return true
+		}
+	}
+	return false
+}
+
+func (state *lexerState) isStringPrefix(word string) bool {
+	for _, prefix := range state.language.StringPrefixes {
+		if strings.EqualFold(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringLength(rest string, quote string, raw bool, multiline bool) int {
+	i := len(quote)
+	for i < len(rest) {
+		if !raw && rest[i] == '\\' {
+			i += 2
+			continue
+		}
+		if rest[i] == '\n' && !multiline {
+			return i // unterminated string, stop at the end of the line
+		}
+		if strings.HasPrefix(rest[i:], quote) {
+			return i + len(quote)
+		}
+		i++
+	}
+	return len(rest)
+}
+
+func isIdentifierStart(char rune) bool {
+	return char == '_' || char == '$' || unicode.IsLetter(char)
+}
+
+func identifierLength(rest string) int {
+	for i, char := range rest {
+		if !(isIdentifierStart(char) || unicode.IsDigit(char)) {
+			return i
+		}
+	}
+	return len(rest)
+}
+
+// isDigit is the digit test of numberLength, only ASCII, so a number is never empty. Other digits,
+// e.g. Arabic-Indic, are operators.
+func isDigit(char rune) bool {
+	return '0' <= char && char <= '9'
+}
+
+func numberLength(rest string) int {
+	i := 0
+	for i < len(rest) {
+		char := rest[i]
+		isExponentSign := (char == '+' || char == '-') && i > 0 && strings.ContainsRune("eEpP", rune(rest[i-1])) &&
+			!strings.HasPrefix(rest, "0x") && !strings.HasPrefix(rest, "0X")
+		if !(char == '.' || char == '_' || isExponentSign || char < utf8.RuneSelf && (isDigit(rune(char)) || unicode.IsLetter(rune(char)))) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+func operatorLength(rest string) int {
+	for _, operator := range OPERATORS {
+		if strings.HasPrefix(rest, operator) {
+			return len(operator)
+		}
+	}
+	_, length := utf8.DecodeRuneInString(rest)
+	return length
+}
@@ -0,0 +1,134 @@
+package lexer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Language describes the lexical rules of a programming language
+type Language struct {
+	Name             string
+	Extensions       []string
+	Keywords         map[string]bool
+	LineComments     []string
+	BlockComments    [][2]string // start and end delimiters
+	StringQuotes     []string    // longest first, e.g. `"""` before `"`
+	RawStringQuotes  []string    // quotes without escapes, e.g. Go's backtick
+	StringPrefixes   []string    // letters that can prefix a string, e.g. Python's r"" or f""
+	MultilineStrings bool        // whether quotes other than raw ones can span lines
+}
+
+func keywords(words string) map[string]bool {
+	keywordMap := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		keywordMap[word] = true
+	}
+	return keywordMap
+}
+
+var GO = &Language{
+	Name:       "go",
+	Extensions: []string{".go"},
+	Keywords: keywords(`break case chan const continue default defer else fallthrough for func go goto
+		if import interface map package range return select struct switch type var
+		true false nil iota append cap close copy delete len make new panic print println recover
+		bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string
+		uint uint8 uint16 uint32 uint64 uintptr any`),
+	LineComments:    []string{"//"},
+	BlockComments:   [][2]string{{"/*", "*/"}},
+	StringQuotes:    []string{`"`, `'`},
+	RawStringQuotes: []string{"`"},
+}
+
+var PYTHON = &Language{
+	Name:       "python",
+	Extensions: []string{".py", ".pyw"},
+	Keywords: keywords(`False None True and as assert async await break class continue def del elif else
+		except finally for from global if import in is lambda nonlocal not or pass raise return try
+		while with yield self print len range int str float list dict set tuple bool`),
+	LineComments:     []string{"#"},
+	StringQuotes:     []string{`"""`, `'''`, `"`, `'`},
+	StringPrefixes:   []string{"r", "b", "f", "u", "rb", "br", "fr", "rf"},
+	MultilineStrings: true,
+}
+
+var JAVA = &Language{
+	Name:       "java",
+	Extensions: []string{".java"},
+	Keywords: keywords(`abstract assert boolean break byte case catch char class const continue default do
+		double else enum extends final finally float for goto if implements import instanceof int
+		interface long native new package private protected public return short static strictfp super
+		switch synchronized this throw throws transient try void volatile while var record yield
+		true false null String Object`),
+	LineComments:     []string{"//"},
+	BlockComments:    [][2]string{{"/*", "*/"}},
+	StringQuotes:     []string{`"""`, `"`, `'`},
+	MultilineStrings: true,
+}
+
+var JAVASCRIPT = &Language{
+	Name:       "javascript",
+	Extensions: []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"},
+	Keywords: keywords(`break case catch class const continue debugger default delete do else export extends
+		finally for function if import in instanceof new return super switch this throw try typeof var
+		void while with yield let static async await of true false null undefined
+		interface type enum implements private protected public readonly abstract declare namespace
+		module as any number string boolean never unknown console`),
+	LineComments:     []string{"//"},
+	BlockComments:    [][2]string{{"/*", "*/"}},
+	StringQuotes:     []string{`"`, `'`},
+	RawStringQuotes:  []string{"`"},
+	MultilineStrings: false,
+}
+
+var C = &Language{
+	Name:       "c",
+	Extensions: []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".hh", ".hxx"},
+	Keywords: keywords(`auto break case char const continue default do double else enum extern float for goto
+		if inline int long register restrict return short signed sizeof static struct switch typedef
+		union unsigned void volatile while bool true false NULL
+		class namespace template typename public private protected virtual override new delete this
+		using try catch throw operator nullptr const_cast static_cast dynamic_cast reinterpret_cast
+		friend explicit mutable constexpr noexcept decltype std
+		include define ifdef ifndef endif pragma undef elif`),
+	LineComments:  []string{"//"},
+	BlockComments: [][2]string{{"/*", "*/"}},
+	StringQuotes:  []string{`"`, `'`},
+}
+
+var LANGUAGES = []*Language{GO, PYTHON, JAVA, JAVASCRIPT, C}
+
+// LanguageFromPath returns the language of a file by its extension, or nil if there's no lexer for it
+func LanguageFromPath(path string) *Language {
+	extension := strings.ToLower(filepath.Ext(path))
+	for _, language := range LANGUAGES {
+		for _, languageExtension := range language.Extensions {
+			if extension == languageExtension {
+				return language
+			}
+		}
+	}
+	return nil
+}
+
+// GENERIC lexes text of an unknown language well enough to normalize it: the keywords of all
+// languages, C and shell style comments, and the usual quotes. Text that is already normalized,
+// like `ID ( LIT )`, lexes to the same tokens.
+var GENERIC = genericLanguage()
+
+func genericLanguage() *Language {
+	allKeywords := make(map[string]bool)
+	for _, language := range LANGUAGES {
+		for keyword := range language.Keywords {
+			allKeywords[keyword] = true
+		}
+	}
+	return &Language{
+		Name:            "generic",
+		Keywords:        allKeywords,
+		LineComments:    []string{"//", "#"},
+		BlockComments:   [][2]string{{"/*", "*/"}},
+		StringQuotes:    []string{`"""`, `'''`, `"`, `'`},
+		RawStringQuotes: []string{"`"},
+	}
+}
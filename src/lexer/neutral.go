@@ -0,0 +1,118 @@
+package lexer
+
+// NEUTRAL_KEYWORDS are the tokens the keywords of all languages are written as in the language-neutral
+// stream, so that code ported to another language keeps its control flow, e.g. Python's elif is
+// written as `else if` and Ruby's rescue as `catch`
+var NEUTRAL_KEYWORDS = map[string][]string{
+	"if": {"if"}, "unless": {"if", "!"},
+	"else": {"else"}, "elif": {"else", "if"}, "elsif": {"else", "if"}, "elseif": {"else", "if"},
+	"for": {"for"}, "foreach": {"for"}, "while": {"while"}, "loop": {"while"}, "do": {"do"},
+	"break": {"break"}, "continue": {"continue"}, "return": {"return"}, "yield": {"yield"},
+	"switch": {"switch"}, "match": {"switch"}, "case": {"case"}, "default": {"default"},
+	"try": {"try"}, "catch": {"catch"}, "except": {"catch"}, "rescue": {"catch"},
+	"finally": {"finally"}, "ensure": {"finally"}, "throw": {"throw"}, "raise": {"throw"},
+	"class": {"class"}, "struct": {"class"}, "interface": {"class"}, "record": {"class"}, "enum": {"class"},
+	"and": {"&&"}, "or": {"||"}, "not": {"!"},
+	"in": {"in"}, "of": {"in"}, "is": {"=="}, "instanceof": {"=="},
+	"del": {"delete"}, "delete": {"delete"},
+}
+
+// NEUTRAL_LITERALS are the keywords that are literals, written as LIT like the other literals
+var NEUTRAL_LITERALS = map[string]bool{
+	"true": true, "false": true, "True": true, "False": true, "null": true, "nil": true, "None": true,
+	"undefined": true, "NULL": true, "nullptr": true,
+}
+
+// NEUTRAL_DROPPED_KEYWORDS only exist in some languages: declarations, types, modifiers and imports.
+// Functions are declared without a keyword in Java and C, so the keywords of other languages are dropped.
+var NEUTRAL_DROPPED_KEYWORDS = map[string]bool{
+	"func": true, "def": true, "function": true, "fun": true, "fn": true, "lambda": true,
+	"var": true, "let": true, "const": true, "val": true, "auto": true, "final": true, "static": true,
+	"public": true, "private": true, "protected": true, "abstract": true, "readonly": true, "override": true,
+	"virtual": true, "inline": true, "extern": true, "register": true, "volatile": true, "signed": true,
+	"unsigned": true, "int": true, "long": true, "short": true, "char": true, "float": true, "double": true,
+	"bool": true, "boolean": true, "byte": true, "void": true, "string": true, "number": true, "any": true,
+	"new": true, "pass": true, "export": true, "async": true, "await": true, "throws": true, "extends": true,
+	"implements": true, "import": true, "package": true, "from": true, "include": true, "using": true,
+	"namespace": true, "mut": true, "pub": true,
+}
+
+// NEUTRAL_OPERATORS are the operators written differently in the language-neutral stream
+var NEUTRAL_OPERATORS = map[string][]string{
+	"===": {"=="}, "!==": {"!="}, ":=": {"="}, "->": {"."}, "::": {"."},
+	"++": {"+=", NORMALIZED_LITERAL}, "--": {"-=", NORMALIZED_LITERAL},
+}
+
+// NEUTRAL_DROPPED_OPERATORS are the block and statement punctuation, which languages without braces
+// or semicolons don't have
+var NEUTRAL_DROPPED_OPERATORS = map[string]bool{";": true, "{": true, "}": true, ":": true, "=>": true}
+
+// Neutralize lexes text and writes out a token stream that is the same for code ported to another
+// language: keywords are written the same in all languages, declarations, types and block punctuation
+// are dropped, and a type followed by a name on a line is one ID, so that the shapes of calls and
+// assignments, like `ID ( ID )` and `ID = LIT`, are left. e.g. `int total = 0;` in Java and `total = 0`
+// in Python are both `ID = LIT`. Languages without a lexer can be lexed with GENERIC, where their keywords
+// that aren't keywords of the lexed languages, like Kotlin's fun, are identifiers.
+func Neutralize(language *Language, text string) *NormalizedText {
+	tokens := Lex(language, text)
+	var sourceTokens []Token
+	var written []string
+	var neutralTokens []Token
+	emit := func(token Token, neutral string) {
+		// `Type name` is one ID, like `name` in languages without types
+		if neutral == NORMALIZED_IDENTIFIER && len(written) > 0 && written[len(written)-1] == NORMALIZED_IDENTIFIER &&
+			sourceTokens[len(sourceTokens)-1].Line == token.Line {
+			return
+		}
+		neutralToken := token
+		switch neutral {
+		case NORMALIZED_IDENTIFIER:
+			neutralToken.Kind = IDENTIFIER
+		case NORMALIZED_LITERAL:
+			neutralToken.Kind = LITERAL
+		default:
+			neutralToken.Kind = KEYWORD
+			neutralToken.Text = neutral
+		}
+		sourceTokens = append(sourceTokens, token)
+		written = append(written, neutral)
+		neutralTokens = append(neutralTokens, neutralToken)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		afterDot := i > 0 && tokens[i-1].Text == "."
+		isWord := token.Kind == KEYWORD || (token.Kind == IDENTIFIER && language == GENERIC && !afterDot)
+		switch {
+		case token.Kind == LITERAL || (isWord && NEUTRAL_LITERALS[token.Text]):
+			emit(token, NORMALIZED_LITERAL)
+		case isWord && (token.Text == "self" || token.Text == "this"):
+			// `self.total` and `total` are the same field, and self is the first parameter of methods
+			if i+1 < len(tokens) && (tokens[i+1].Text == "." || tokens[i+1].Text == ",") {
+				i++
+			}
+		case isWord && NEUTRAL_DROPPED_KEYWORDS[token.Text]:
+		case isWord && NEUTRAL_KEYWORDS[token.Text] != nil:
+			for _, neutral := range NEUTRAL_KEYWORDS[token.Text] {
+				emit(token, neutral)
+			}
+		case token.Kind == KEYWORD || token.Kind == IDENTIFIER:
+			// builtins of one language, like len or console, are names
+			emit(token, NORMALIZED_IDENTIFIER)
+		case NEUTRAL_DROPPED_OPERATORS[token.Text]:
+		case token.Text == "[" && i+1 < len(tokens) && tokens[i+1].Text == "]":
+			// the brackets of array types, e.g. `int[] numbers`
+			i++
+		case NEUTRAL_OPERATORS[token.Text] != nil:
+			for _, neutral := range NEUTRAL_OPERATORS[token.Text] {
+				emit(token, neutral)
+			}
+		default:
+			emit(token, token.Text)
+		}
+	}
+
+	normalizedText := writeTokens(text, sourceTokens, written)
+	normalizedText.Tokens = neutralTokens
+	return normalizedText
+}
@@ -0,0 +1,68 @@
+package lexer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// NormalizedText is the token stream of a source written out as text, one line
+// per source line that has tokens, with a map back to the source.
+type NormalizedText struct {
+	Text      string
+	SourceMap []int // byte offset in the source for every byte of Text, plus one for len(Text)
+	Tokens    []Token
+}
+
+// Normalize lexes text and writes the normalized tokens out,
+// e.g. `total := price * 2 // vat` becomes `ID := ID * LIT`
+func Normalize(language *Language, text string) *NormalizedText {
+	tokens := Lex(language, text)
+	written := make([]string, len(tokens))
+	for i, token := range tokens {
+		written[i] = token.Normalized()
+	}
+	normalizedText := writeTokens(text, tokens, written)
+	normalizedText.Tokens = tokens
+	return normalizedText
+}
+
+// writeTokens writes written[i] out for every token of the source, on one line for every source line,
+// with the source map. A token can be written several times, e.g. as two tokens.
+func writeTokens(text string, tokens []Token, written []string) *NormalizedText {
+	var normalizedText strings.Builder
+	sourceMap := make([]int, 0, len(text))
+	lastOffset := 0
+
+	for i, token := range tokens {
+		normalizedToken := written[i]
+		for j := 0; j < len(normalizedToken); j++ {
+			// spread the token over the source, so both ends of a token map to the ends in the source,
+			// at the start of a rune, so the source can be sliced there
+			offset := j * len(token.Text) / len(normalizedToken)
+			for offset > 0 && !utf8.RuneStart(token.Text[offset]) {
+				offset--
+			}
+			// a token written twice maps after its first time, so the source map never goes back
+			if token.Offset+offset > lastOffset {
+				lastOffset = token.Offset + offset
+			}
+			sourceMap = append(sourceMap, lastOffset)
+		}
+		normalizedText.WriteString(normalizedToken)
+
+		tokenEnd := token.Offset + len(token.Text)
+		if i+1 < len(tokens) && tokens[i+1].Line == token.Line+strings.Count(token.Text, "\n") {
+			normalizedText.WriteByte(' ')
+		} else {
+			normalizedText.WriteByte('\n')
+		}
+		sourceMap = append(sourceMap, tokenEnd)
+		lastOffset = tokenEnd
+	}
+	sourceMap = append(sourceMap, len(text))
+
+	return &NormalizedText{
+		Text:      normalizedText.String(),
+		SourceMap: sourceMap,
+	}
+}
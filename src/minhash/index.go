@@ -0,0 +1,144 @@
+package minhash
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+)
+
+const SHINGLE_LENGTH = 4 // tokens
+const DEFAULT_BANDS = 32
+const DEFAULT_ROWS = 4
+
+// Signature is the minimum hash of the shingles of a file under every hash function. The share of
+// equal values of two signatures estimates the Jaccard similarity of their shingles.
+type Signature []uint64
+
+// Shingles are the hashes of every SHINGLE_LENGTH consecutive tokens, or of all tokens if there are fewer
+func Shingles(tokens []string) map[uint64]bool {
+	shingles := make(map[uint64]bool)
+	for i := 0; i < len(tokens); i++ {
+		end := i + SHINGLE_LENGTH
+		if end > len(tokens) {
+			if i > 0 {
+				break
+			}
+			end = len(tokens)
+		}
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(tokens[i:end], " ")))
+		shingles[hash.Sum64()] = true
+	}
+	return shingles
+}
+
+// Sign returns the signature of the shingles of tokens with hashes values, nil without tokens
+func Sign(tokens []string, hashes int) Signature {
+	shingles := Shingles(tokens)
+	if len(shingles) == 0 {
+		return nil
+	}
+	seeds := make([]uint64, hashes)
+	signature := make(Signature, hashes)
+	for i := range signature {
+		seeds[i] = mix(uint64(i))
+		signature[i] = math.MaxUint64
+	}
+	for shingle := range shingles {
+		for i, seed := range seeds {
+			if value := mix(shingle ^ seed); value < signature[i] {
+				signature[i] = value
+			}
+		}
+	}
+	return signature
+}
+
+// Similarity estimates the Jaccard similarity of the shingles of two signatures of the same length
+func Similarity(signature1 Signature, signature2 Signature) float64 {
+	if len(signature1) == 0 || len(signature1) != len(signature2) {
+		return 0
+	}
+	equal := 0
+	for i := range signature1 {
+		if signature1[i] == signature2[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(signature1))
+}
+
+// Threshold is the Jaccard similarity at which two files become candidates with even odds,
+// (1/bands)^(1/rows). More bands or fewer rows find less similar pairs, with more false candidates.
+func Threshold(bands int, rows int) float64 {
+	return math.Pow(1/float64(bands), 1/float64(rows))
+}
+
+// mix is the splitmix64 finalizer, a cheap hash of a 64-bit value
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// Index is an LSH index of signatures of bands*rows values: two signatures are candidates if all the
+// rows of one of their bands are equal, which finds similar files without comparing every pair
+type Index struct {
+	Bands   int
+	Rows    int
+	buckets []map[uint64][]string // by band, the keys of the signatures by the hash of their rows
+}
+
+func NewIndex(bands int, rows int) *Index {
+	buckets := make([]map[uint64][]string, bands)
+	for band := range buckets {
+		buckets[band] = make(map[uint64][]string)
+	}
+	return &Index{Bands: bands, Rows: rows, buckets: buckets}
+}
+
+// Hashes is the length of the signatures of the index
+func (index *Index) Hashes() int {
+	return index.Bands * index.Rows
+}
+
+// Add adds the signature under key. Signatures of another length, like the nil signature of an empty
+// file, are never candidates.
+func (index *Index) Add(key string, signature Signature) {
+	if len(signature) != index.Hashes() {
+		return
+	}
+	for band := range index.buckets {
+		bandHash := index.bandHash(signature, band)
+		index.buckets[band][bandHash] = append(index.buckets[band][bandHash], key)
+	}
+}
+
+// Candidates are the keys of the signatures that share a band with signature, in order
+func (index *Index) Candidates(signature Signature) []string {
+	if len(signature) != index.Hashes() {
+		return nil
+	}
+	found := make(map[string]bool)
+	for band := range index.buckets {
+		for _, key := range index.buckets[band][index.bandHash(signature, band)] {
+			found[key] = true
+		}
+	}
+	candidates := make([]string, 0, len(found))
+	for key := range found {
+		candidates = append(candidates, key)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func (index *Index) bandHash(signature Signature, band int) uint64 {
+	bandHash := uint64(band)
+	for _, value := range signature[band*index.Rows : (band+1)*index.Rows] {
+		bandHash = mix(bandHash ^ value)
+	}
+	return bandHash
+}
@@ -0,0 +1,107 @@
+package obfuscation
+
+import (
+	"hercules/src/lexer"
+	"hercules/src/util"
+	"math/rand"
+	"strings"
+)
+
+// COMMENT_WORDS are what the rewritten comments are made of
+var COMMENT_WORDS = []string{
+	"compute", "the", "value", "update", "loop", "over", "all", "items", "check", "result", "helper",
+	"return", "handle", "input", "output", "state", "store", "list", "next", "get", "set", "a", "new",
+	"if", "needed", "for", "each", "element", "this", "function", "does", "main", "logic", "here",
+}
+
+// DIRECTIVE_PREFIXES start comments that tell the compiler or shell something, which are kept
+var DIRECTIVE_PREFIXES = []string{"//go:", "// +build", "#!", "# -*-"}
+
+// removeComments drops the comments, and the lines that were only a comment
+func removeComments(code *sourceCode, _ *rand.Rand) (string, bool) {
+	_, comments := lexer.LexWithComments(code.language, code.text)
+	text := code.text
+	changed := false
+	// from the end, so the offsets of the comments before stay right
+	for i := len(comments) - 1; i >= 0; i-- {
+		comment := comments[i]
+		if isDirective(comment.Text) {
+			continue
+		}
+		start, end := comment.Offset, comment.Offset+len(comment.Text)
+		lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+		lineEnd := strings.IndexByte(text[end:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(text)
+		} else {
+			lineEnd += end
+		}
+		if isBlank(text[lineStart:start]) && isBlank(text[end:lineEnd]) {
+			// the whole line, with its newline
+			start, end = lineStart, util.Min(lineEnd+1, len(text))
+		} else {
+			start = lineStart + len(strings.TrimRight(text[lineStart:start], " \t"))
+		}
+		text = text[:start] + text[end:]
+		changed = true
+	}
+	return text, changed
+}
+
+// rewriteComments replaces the words of the comments with about as many others, keeping the delimiters
+func rewriteComments(code *sourceCode, random *rand.Rand) (string, bool) {
+	_, comments := lexer.LexWithComments(code.language, code.text)
+	var builder strings.Builder
+	last := 0
+	changed := false
+	for _, comment := range comments {
+		if isDirective(comment.Text) {
+			continue
+		}
+		opening, closing := commentDelimiters(code.language, comment.Text)
+		body := comment.Text[len(opening) : len(comment.Text)-len(closing)]
+		numberOfWords := len(strings.Fields(body))
+		if numberOfWords == 0 {
+			continue
+		}
+		words := make([]string, numberOfWords)
+		for i := range words {
+			words[i] = COMMENT_WORDS[random.Intn(len(COMMENT_WORDS))]
+		}
+		builder.WriteString(code.text[last:comment.Offset])
+		builder.WriteString(opening + " " + strings.Join(words, " "))
+		if closing != "" {
+			builder.WriteString(" " + closing)
+		}
+		last = comment.Offset + len(comment.Text)
+		changed = true
+	}
+	builder.WriteString(code.text[last:])
+	return builder.String(), changed
+}
+
+func commentDelimiters(language *lexer.Language, comment string) (string, string) {
+	for _, blockComment := range language.BlockComments {
+		if strings.HasPrefix(comment, blockComment[0]) {
+			if strings.HasSuffix(comment, blockComment[1]) && len(comment) >= len(blockComment[0])+len(blockComment[1]) {
+				return blockComment[0], blockComment[1]
+			}
+			return blockComment[0], ""
+		}
+	}
+	for _, lineComment := range language.LineComments {
+		if strings.HasPrefix(comment, lineComment) {
+			return lineComment, ""
+		}
+	}
+	return "", ""
+}
+
+func isDirective(comment string) bool {
+	for _, prefix := range DIRECTIVE_PREFIXES {
+		if strings.HasPrefix(comment, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package obfuscation
+
+import (
+	"fmt"
+	"hercules/src/syntax_tree"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// DEAD_STATEMENTS are statements that do nothing, by lexer.Language.Name, formatted with a number
+// for unique names
+var DEAD_STATEMENTS = map[string][]string{
+	"go":         {"if false { _ = %[1]d }", "for false { break }", "_ = %[1]d"},
+	"python":     {"if False: pass", "unused%[1]d = None", "pass"},
+	"java":       {"if (false) { int unused%[1]d = 0; }", "int unused%[1]d = %[1]d;"},
+	"javascript": {"if (false) { let unused%[1]d = 0; }", "void 0;", "let unused%[1]d = null;"},
+	"c":          {"if (0) { }", "(void) %[1]d;"},
+}
+
+// insertDeadCode puts a dead statement at the start of about half of the functions, at least one
+func insertDeadCode(code *sourceCode, random *rand.Rand) (string, bool) {
+	deadStatements, ok := DEAD_STATEMENTS[code.language.Name]
+	if !ok || !syntax_tree.IsSupported(code.path) {
+		return code.text, false
+	}
+	root, err := syntax_tree.Parse(code.text, code.path)
+	if err != nil {
+		return code.text, false
+	}
+	lines := splitLines(code.text)
+
+	// the first line of the body and its indentation, for every function whose body starts on the next line
+	bodyStarts := make(map[int]string)
+	root.Walk(func(node *syntax_tree.Node) {
+		if node.Category() != syntax_tree.CATEGORY_FUNCTION {
+			return
+		}
+		header := strings.TrimSpace(lines[node.StartLine-1])
+		if !strings.HasSuffix(header, "{") && !(code.language.Name == "python" && strings.HasSuffix(header, ":")) {
+			return
+		}
+		for i := node.StartLine; i < node.EndLine-1 && i < len(lines); i++ {
+			if isBlank(lines[i]) {
+				continue
+			}
+			if len(indentation(lines[i])) > len(indentation(lines[node.StartLine-1])) {
+				bodyStarts[i] = indentation(lines[i])
+			}
+			return
+		}
+	})
+	if len(bodyStarts) == 0 {
+		return code.text, false
+	}
+
+	var insertAt []int
+	for line := range bodyStarts {
+		insertAt = append(insertAt, line)
+	}
+	sort.Ints(insertAt)
+	chosen := random.Perm(len(insertAt))[:(len(insertAt)+1)/2]
+	inserts := make(map[int]string)
+	for i, index := range chosen {
+		line := insertAt[index]
+		statement := deadStatements[random.Intn(len(deadStatements))]
+		if strings.Contains(statement, "%") {
+			statement = fmt.Sprintf(statement, i+1)
+		}
+		inserts[line] = bodyStarts[line] + statement
+	}
+
+	var inserted []string
+	for i, line := range lines {
+		if statement, ok := inserts[i]; ok {
+			inserted = append(inserted, statement)
+		}
+		inserted = append(inserted, line)
+	}
+	return strings.Join(inserted, "\n"), true
+}
@@ -0,0 +1,161 @@
+package obfuscation
+
+import (
+	"hercules/src/syntax_tree"
+	"math/rand"
+	"strings"
+)
+
+// WRAPPER_KINDS wrap a function with what belongs to it, like a decorator or export
+var WRAPPER_KINDS = []string{"decorated_definition", "export_statement"}
+
+// lineBlock is the lines [Start, End) of the text, 0-based
+type lineBlock struct {
+	Start int
+	End   int
+}
+
+// functionBlocks finds the largest group of sibling functions of the file, e.g. the functions of a Go
+// file or the methods of a Java class, as blocks of whole lines with the comments and decorators above
+// them. There are none if the file can't be parsed or functions share lines with other code.
+func functionBlocks(code *sourceCode, lines []string) []lineBlock {
+	if !syntax_tree.IsSupported(code.path) {
+		return nil
+	}
+	root, err := syntax_tree.Parse(code.text, code.path)
+	if err != nil {
+		return nil
+	}
+
+	var bestBlocks []lineBlock
+	root.Walk(func(node *syntax_tree.Node) {
+		blocks, ok := siblingFunctionBlocks(node, lines)
+		if ok && len(blocks) > len(bestBlocks) {
+			bestBlocks = blocks
+		}
+	})
+	if len(bestBlocks) < 2 {
+		return nil
+	}
+	return bestBlocks
+}
+
+func siblingFunctionBlocks(parent *syntax_tree.Node, lines []string) ([]lineBlock, bool) {
+	var blocks []lineBlock
+	previousEnd := 0 // end line of the previous child, 1-based
+	for _, child := range parent.Children {
+		if strings.Contains(child.Kind, "comment") {
+			// tree-sitter keeps comments, which are attached to the function below them
+			continue
+		}
+		if child.StartLine <= previousEnd {
+			// shares a line with the previous child
+			if isFunction(child) || len(blocks) > 0 && blocks[len(blocks)-1].End >= child.StartLine {
+				return nil, false
+			}
+		}
+		if isFunction(child) {
+			start := child.StartLine - 1
+			for start > 0 && start > previousEnd && isAttachedLine(lines[start-1]) {
+				start--
+			}
+			blocks = append(blocks, lineBlock{Start: start, End: child.EndLine})
+		}
+		previousEnd = child.EndLine
+	}
+	return blocks, true
+}
+
+func isFunction(node *syntax_tree.Node) bool {
+	if node.Category() == syntax_tree.CATEGORY_FUNCTION {
+		return true
+	}
+	for _, kind := range WRAPPER_KINDS {
+		if node.Kind != kind {
+			continue
+		}
+		for _, child := range node.Children {
+			if child.Category() == syntax_tree.CATEGORY_FUNCTION {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isAttachedLine is true for comments and annotations, which belong to the function below them
+func isAttachedLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"//", "#", "/*", "*", "@"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// reorderFunctions shuffles the largest group of sibling functions
+func reorderFunctions(code *sourceCode, random *rand.Rand) (string, bool) {
+	lines := splitLines(code.text)
+	blocks := functionBlocks(code, lines)
+	if blocks == nil {
+		return code.text, false
+	}
+
+	order := random.Perm(len(blocks))
+	if isIdentity(order) {
+		// rotate, so the order always changes
+		for i := range order {
+			order[i] = (i + 1) % len(order)
+		}
+	}
+
+	var reordered []string
+	last := 0
+	for i, block := range blocks {
+		reordered = append(reordered, lines[last:block.Start]...)
+		moved := blocks[order[i]]
+		reordered = append(reordered, lines[moved.Start:moved.End]...)
+		last = block.End
+	}
+	reordered = append(reordered, lines[last:]...)
+	return strings.Join(reordered, "\n"), true
+}
+
+// splitFile moves the second half of the largest group of sibling functions to a second file, with the
+// lines before and after the group, like the package and imports or the class around the methods
+func splitFile(code *sourceCode) (string, string, bool) {
+	lines := splitLines(code.text)
+	blocks := functionBlocks(code, lines)
+	if blocks == nil {
+		return "", "", false
+	}
+
+	moved := blocks[len(blocks)/2:]
+	var part1, part2 []string
+	last := 0
+	for _, block := range moved {
+		part1 = append(part1, lines[last:block.Start]...)
+		last = block.End
+	}
+	part1 = append(part1, lines[last:]...)
+
+	part2 = append(part2, lines[:blocks[0].Start]...)
+	for i, block := range moved {
+		if i > 0 {
+			part2 = append(part2, "")
+		}
+		part2 = append(part2, lines[block.Start:block.End]...)
+	}
+	part2 = append(part2, lines[moved[len(moved)-1].End:]...)
+	return strings.Join(part1, "\n"), strings.Join(part2, "\n"), true
+}
+
+func isIdentity(order []int) bool {
+	for i, j := range order {
+		if i != j {
+			return false
+		}
+	}
+	return true
+}
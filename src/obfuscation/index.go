@@ -0,0 +1,118 @@
+package obfuscation
+
+import (
+	"fmt"
+	"hercules/src/lexer"
+	"hercules/src/util"
+	"math/rand"
+	"strings"
+)
+
+// The obfuscations a plagiarist might use to disguise a copy
+const (
+	OBFUSCATION_RENAME           = "rename"           // consistently rename the identifiers declared in the file
+	OBFUSCATION_REORDER          = "reorder"          // shuffle the functions
+	OBFUSCATION_DEAD_CODE        = "dead_code"        // insert statements that do nothing at the start of functions
+	OBFUSCATION_LOOP_CONVERSION  = "loop_conversion"  // turn counting for loops into while loops
+	OBFUSCATION_REMOVE_COMMENTS  = "remove_comments"  // drop the comments
+	OBFUSCATION_REWRITE_COMMENTS = "rewrite_comments" // replace the words of the comments
+	OBFUSCATION_REFORMAT         = "reformat"         // change the indentation, blank lines and spacing after commas
+	OBFUSCATION_SPLIT            = "split"            // move half of the functions to a second file
+)
+
+// ALL_OBFUSCATIONS in the order they are applied, split last as it makes two files.
+// Comments are removed before they are rewritten, so a copy with both only has them removed.
+var ALL_OBFUSCATIONS = []string{
+	OBFUSCATION_RENAME, OBFUSCATION_REORDER, OBFUSCATION_DEAD_CODE, OBFUSCATION_LOOP_CONVERSION,
+	OBFUSCATION_REMOVE_COMMENTS, OBFUSCATION_REWRITE_COMMENTS, OBFUSCATION_REFORMAT, OBFUSCATION_SPLIT,
+}
+
+// transformation rewrites the code of a file, and returns false if it found nothing to change
+type transformation func(code *sourceCode, random *rand.Rand) (string, bool)
+
+var TRANSFORMATIONS = map[string]transformation{
+	OBFUSCATION_RENAME:           renameIdentifiers,
+	OBFUSCATION_REORDER:          reorderFunctions,
+	OBFUSCATION_DEAD_CODE:        insertDeadCode,
+	OBFUSCATION_LOOP_CONVERSION:  convertLoops,
+	OBFUSCATION_REMOVE_COMMENTS:  removeComments,
+	OBFUSCATION_REWRITE_COMMENTS: rewriteComments,
+	OBFUSCATION_REFORMAT:         reformat,
+}
+
+type sourceCode struct {
+	text     string
+	path     string // only its extension is used, for the language
+	language *lexer.Language
+}
+
+// ParseObfuscations parses a comma separated list of obfuscations, e.g. "rename,reorder", in ALL_OBFUSCATIONS order
+func ParseObfuscations(obfuscationsList string) ([]string, error) {
+	requested := make(map[string]bool)
+	for _, obfuscation := range strings.Split(obfuscationsList, ",") {
+		obfuscation = strings.TrimSpace(strings.ToLower(obfuscation))
+		if obfuscation == "" {
+			continue
+		}
+		if _, ok := TRANSFORMATIONS[obfuscation]; !ok && obfuscation != OBFUSCATION_SPLIT {
+			return nil, fmt.Errorf("unknown obfuscation %q, must be one of %s", obfuscation, strings.Join(ALL_OBFUSCATIONS, ", "))
+		}
+		requested[obfuscation] = true
+	}
+	var obfuscations []string
+	for _, obfuscation := range ALL_OBFUSCATIONS {
+		if requested[obfuscation] {
+			obfuscations = append(obfuscations, obfuscation)
+		}
+	}
+	if len(obfuscations) == 0 {
+		return nil, fmt.Errorf("no obfuscations given")
+	}
+	return obfuscations, nil
+}
+
+// IsSupported is true if the file's language can be obfuscated
+func IsSupported(path string) bool {
+	return lexer.LanguageFromPath(path) != nil
+}
+
+// Obfuscate applies the obfuscations to the text of the file at path, in ALL_OBFUSCATIONS order,
+// and returns the obfuscated files with the obfuscations that changed something. There are two
+// files if it was split, and one otherwise.
+func Obfuscate(text string, path string, obfuscations []string, random *rand.Rand) ([]string, []string) {
+	code := sourceCode{text: text, path: path, language: lexer.LanguageFromPath(path)}
+	if code.language == nil {
+		return []string{text}, nil
+	}
+
+	var applied []string
+	for _, obfuscation := range ALL_OBFUSCATIONS {
+		transform, ok := TRANSFORMATIONS[obfuscation]
+		if !ok || !util.Contains(obfuscations, obfuscation) {
+			continue
+		}
+		if obfuscatedText, changed := transform(&code, random); changed {
+			code.text = obfuscatedText
+			applied = append(applied, obfuscation)
+		}
+	}
+	if util.Contains(obfuscations, OBFUSCATION_SPLIT) {
+		if part1, part2, ok := splitFile(&code); ok {
+			return []string{part1, part2}, append(applied, OBFUSCATION_SPLIT)
+		}
+	}
+	return []string{code.text}, applied
+}
+
+// splitLines splits text into lines without their newlines
+func splitLines(text string) []string {
+	return strings.Split(text, "\n")
+}
+
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
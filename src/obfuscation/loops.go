@@ -0,0 +1,190 @@
+package obfuscation
+
+import (
+	"hercules/src/lexer"
+	"hercules/src/syntax_tree"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FOR_LOOP_KINDS are the syntax tree kinds of counting for loops
+var FOR_LOOP_KINDS = []string{"ForStmt", "for_statement"}
+
+var GO_FOR_LOOP = regexp.MustCompile(`^(\s*)for\s+([^;{]*);([^;{]*);([^{]*?)\s*\{\s*$`)
+var C_FOR_LOOP = regexp.MustCompile(`^(\s*)for\s*\(([^;]*);([^;]*);(.*)\)\s*\{\s*$`)
+var PYTHON_RANGE_LOOP = regexp.MustCompile(`^(\s*)for\s+(\w+)\s+in\s+range\((.*)\)\s*:\s*$`)
+
+// convertLoops turns the counting for loops, whose body is a block of whole lines without a continue,
+// into while loops with the update at the end of the body
+func convertLoops(code *sourceCode, _ *rand.Rand) (string, bool) {
+	if !syntax_tree.IsSupported(code.path) {
+		return code.text, false
+	}
+	root, err := syntax_tree.Parse(code.text, code.path)
+	if err != nil {
+		return code.text, false
+	}
+	lines := splitLines(code.text)
+
+	var loops []*syntax_tree.Node
+	root.Walk(func(node *syntax_tree.Node) {
+		for _, kind := range FOR_LOOP_KINDS {
+			if node.Kind == kind {
+				loops = append(loops, node)
+			}
+		}
+	})
+	// from the end, so the lines of the loops before stay where they are,
+	// and inner loops first, as a converted outer loop has moved them
+	sort.Slice(loops, func(i, j int) bool {
+		return loops[i].StartLine > loops[j].StartLine
+	})
+
+	changed := false
+	convertedStart := len(lines) + 1 // start line of the last converted loop, which the next must end before
+	for _, loop := range loops {
+		if loop.EndLine <= loop.StartLine || loop.EndLine >= convertedStart || loop.EndLine > len(lines) {
+			continue
+		}
+		header := lines[loop.StartLine-1]
+		body := lines[loop.StartLine : loop.EndLine-1]
+		if hasContinue(code.language, lines[loop.StartLine:loop.EndLine]) {
+			continue
+		}
+		var converted []string
+		var ok bool
+		switch code.language.Name {
+		case "go":
+			converted, ok = convertBraceLoop(GO_FOR_LOOP, header, body, lines[loop.EndLine-1], true)
+		case "python":
+			converted, ok = convertRangeLoop(header, lines[loop.StartLine:loop.EndLine])
+		default:
+			converted, ok = convertBraceLoop(C_FOR_LOOP, header, body, lines[loop.EndLine-1], false)
+		}
+		if !ok {
+			continue
+		}
+		lines = append(lines[:loop.StartLine-1], append(converted, lines[loop.EndLine:]...)...)
+		convertedStart = loop.StartLine
+		changed = true
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// convertBraceLoop turns `for (init; cond; post) {` into a block with init and `while (cond) {`,
+// or `for cond {` for Go, with post at the end of the body
+func convertBraceLoop(pattern *regexp.Regexp, header string, body []string, footer string, isGo bool) ([]string, bool) {
+	match := pattern.FindStringSubmatch(header)
+	if match == nil || strings.TrimSpace(footer) != "}" {
+		return nil, false
+	}
+	indent := match[1]
+	init, condition, post := strings.TrimSpace(match[2]), strings.TrimSpace(match[3]), strings.TrimSpace(match[4])
+	bodyIndent := indent + "\t"
+	for _, line := range body {
+		if !isBlank(line) {
+			bodyIndent = indentation(line)
+			break
+		}
+	}
+
+	converted := []string{indent + "{"}
+	if init != "" {
+		converted = append(converted, indent+statement(init, isGo))
+	}
+	switch {
+	case isGo && condition == "":
+		converted = append(converted, indent+"for {")
+	case isGo:
+		converted = append(converted, indent+"for "+condition+" {")
+	case condition == "":
+		converted = append(converted, indent+"while (true) {")
+	default:
+		converted = append(converted, indent+"while ("+condition+") {")
+	}
+	converted = append(converted, body...)
+	if post != "" {
+		converted = append(converted, bodyIndent+statement(post, isGo))
+	}
+	return append(converted, indent+"}", indent+"}"), true
+}
+
+func statement(code string, isGo bool) string {
+	if isGo {
+		return code
+	}
+	return code + ";"
+}
+
+// convertRangeLoop turns `for i in range(start, stop):` into `i = start` and `while i < stop:`,
+// with `i += 1` at the end of the body
+func convertRangeLoop(header string, body []string) ([]string, bool) {
+	match := PYTHON_RANGE_LOOP.FindStringSubmatch(header)
+	if match == nil {
+		return nil, false
+	}
+	indent, variable := match[1], match[2]
+	arguments := splitArguments(match[3])
+	start, stop := "0", ""
+	switch len(arguments) {
+	case 1:
+		stop = arguments[0]
+	case 2:
+		start, stop = arguments[0], arguments[1]
+	default:
+		return nil, false
+	}
+
+	bodyIndent := ""
+	for _, line := range body {
+		if isBlank(line) {
+			continue
+		}
+		// an else clause, or the body ended before the loop did
+		if len(indentation(line)) <= len(indent) {
+			return nil, false
+		}
+		if bodyIndent == "" {
+			bodyIndent = indentation(line)
+		}
+	}
+	if bodyIndent == "" {
+		return nil, false
+	}
+
+	converted := []string{indent + variable + " = " + start, indent + "while " + variable + " < " + stop + ":"}
+	converted = append(converted, body...)
+	return append(converted, bodyIndent+variable+" += 1"), true
+}
+
+// splitArguments splits arguments at the commas outside brackets
+func splitArguments(arguments string) []string {
+	var split []string
+	depth := 0
+	last := 0
+	for i, char := range arguments {
+		switch char {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				split = append(split, strings.TrimSpace(arguments[last:i]))
+				last = i + 1
+			}
+		}
+	}
+	return append(split, strings.TrimSpace(arguments[last:]))
+}
+
+func hasContinue(language *lexer.Language, body []string) bool {
+	for _, token := range lexer.Lex(language, strings.Join(body, "\n")) {
+		if token.Text == "continue" {
+			return true
+		}
+	}
+	return false
+}
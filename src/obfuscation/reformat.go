@@ -0,0 +1,126 @@
+package obfuscation
+
+import (
+	"hercules/src/lexer"
+	"math/rand"
+	"strings"
+)
+
+// INDENTATIONS are the indentation units a file is reformatted to
+var INDENTATIONS = []string{"\t", "  ", "    "}
+
+const BLANK_LINE_INSERTION_RATE = 8 // one in this many lines gets a blank line after it
+
+// reformat changes the indentation unit, the spaces after commas, and the blank lines, but not inside
+// multiline strings
+func reformat(code *sourceCode, random *rand.Rand) (string, bool) {
+	text := respaceCommas(code)
+	tokens := lexer.Lex(code.language, text)
+	lines := splitLines(text)
+
+	// the lines inside a multiline literal, which mustn't change
+	insideLiteral := make([]bool, len(lines)+1)
+	for _, token := range tokens {
+		if token.Kind != lexer.LITERAL {
+			continue
+		}
+		for line := token.Line; line < token.Line+strings.Count(token.Text, "\n"); line++ {
+			insideLiteral[line] = true // 0-based index of the 1-based line after
+		}
+	}
+
+	unit := indentationUnit(lines)
+	var newUnits []string
+	for _, indentation := range INDENTATIONS {
+		if indentation != unit {
+			newUnits = append(newUnits, indentation)
+		}
+	}
+	newUnit := newUnits[random.Intn(len(newUnits))]
+
+	var reformatted []string
+	for i, line := range lines {
+		if insideLiteral[i] {
+			reformatted = append(reformatted, line)
+			continue
+		}
+		if isBlank(line) && random.Intn(2) == 0 {
+			continue
+		}
+		reformatted = append(reformatted, reindent(line, unit, newUnit))
+		if !isBlank(line) && i+1 < len(lines) && !insideLiteral[i+1] && random.Intn(BLANK_LINE_INSERTION_RATE) == 0 {
+			reformatted = append(reformatted, "")
+		}
+	}
+	reformattedText := strings.Join(reformatted, "\n")
+	return reformattedText, reformattedText != code.text
+}
+
+// respaceCommas removes the space after the commas if most have one, and adds one otherwise
+func respaceCommas(code *sourceCode) string {
+	tokens := lexer.Lex(code.language, code.text)
+	var commaOffsets []int
+	spaced := 0
+	for _, token := range tokens {
+		if token.Text != "," {
+			continue
+		}
+		next := token.Offset + 1
+		if next >= len(code.text) || code.text[next] == '\n' || code.text[next] == '\r' {
+			continue
+		}
+		commaOffsets = append(commaOffsets, next)
+		if code.text[next] == ' ' {
+			spaced++
+		}
+	}
+	addSpaces := spaced*2 < len(commaOffsets)
+
+	var builder strings.Builder
+	last := 0
+	for _, offset := range commaOffsets {
+		builder.WriteString(code.text[last:offset])
+		last = offset
+		switch {
+		case addSpaces && code.text[offset] != ' ':
+			builder.WriteString(" ")
+		case !addSpaces:
+			last = offset + len(code.text[offset:]) - len(strings.TrimLeft(code.text[offset:], " "))
+		}
+	}
+	builder.WriteString(code.text[last:])
+	return builder.String()
+}
+
+// indentationUnit is a tab if lines are indented with tabs, and the fewest spaces a line is indented with otherwise
+func indentationUnit(lines []string) string {
+	unit := ""
+	for _, line := range lines {
+		lineIndentation := indentation(line)
+		if isBlank(line) || lineIndentation == "" {
+			continue
+		}
+		if lineIndentation[0] == '\t' {
+			return "\t"
+		}
+		if unit == "" || len(lineIndentation) < len(unit) {
+			unit = lineIndentation
+		}
+	}
+	if unit == "" {
+		return "\t"
+	}
+	return unit
+}
+
+// reindent replaces the indentation units at the start of the line with newUnit, keeping the rest,
+// like spaces aligning a continued line
+func reindent(line string, unit string, newUnit string) string {
+	levels := 0
+	rest := line
+	for strings.HasPrefix(rest, unit) {
+		rest = rest[len(unit):]
+		levels++
+	}
+	return strings.Repeat(newUnit, levels) + rest
+}
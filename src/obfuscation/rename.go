@@ -0,0 +1,108 @@
+package obfuscation
+
+import (
+	"fmt"
+	"hercules/src/lexer"
+	"hercules/src/tfidf"
+	"math/rand"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NEW_NAMES are what a student would rename variables to
+var NEW_NAMES = []string{
+	"value", "item", "data", "result", "temp", "count", "index", "node", "entry", "buffer",
+	"total", "flag", "key", "elem", "current", "acc", "output", "input", "state", "info",
+}
+
+// NEVER_RENAMED are identifiers with a meaning to the language or its tools
+var NEVER_RENAMED = map[string]bool{"main": true, "init": true, "_": true, "self": true, "this": true, "cls": true}
+
+// NOT_DECLARING_KEYWORDS start lines with names from outside the file
+var NOT_DECLARING_KEYWORDS = map[string]bool{"package": true, "import": true, "from": true}
+
+// renameIdentifiers renames every identifier the file declares, the same everywhere. Identifiers only
+// used next to a dot, like the packages and functions in fmt.Println, package and import names, and
+// the standard library names in the stopwords of the language are from elsewhere, so they keep their names.
+func renameIdentifiers(code *sourceCode, random *rand.Rand) (string, bool) {
+	tokens := lexer.Lex(code.language, code.text)
+	stopWords := make(map[string]bool)
+	for _, word := range tfidf.StopWords(code.language.Name) {
+		stopWords[word] = true
+	}
+
+	// the names on import and package lines
+	external := make(map[string]bool)
+	externalLine := 0
+	for _, token := range tokens {
+		if NOT_DECLARING_KEYWORDS[token.Text] {
+			externalLine = token.Line
+		} else if token.Line == externalLine && token.Kind == lexer.IDENTIFIER {
+			external[token.Text] = true
+		}
+	}
+
+	declared := make(map[string]bool)
+	existing := make(map[string]bool)
+	for i, token := range tokens {
+		existing[token.Text] = true
+		if token.Kind != lexer.IDENTIFIER || isNextToDot(tokens, i) || external[token.Text] {
+			continue
+		}
+		if NEVER_RENAMED[token.Text] || stopWords[strings.ToLower(token.Text)] || isDunder(token.Text) {
+			continue
+		}
+		declared[token.Text] = true
+	}
+	if len(declared) == 0 {
+		return code.text, false
+	}
+
+	// in order of appearance, so the same seed gives the same names
+	newNames := make(map[string]string)
+	for _, token := range tokens {
+		if !declared[token.Text] || newNames[token.Text] != "" {
+			continue
+		}
+		baseName := NEW_NAMES[random.Intn(len(NEW_NAMES))]
+		newName := matchCase(token.Text, baseName)
+		for suffix := 2; existing[newName] || stopWords[strings.ToLower(newName)]; suffix++ {
+			newName = matchCase(token.Text, fmt.Sprintf("%s%d", baseName, suffix))
+		}
+		existing[newName] = true
+		newNames[token.Text] = newName
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, token := range tokens {
+		if newName, ok := newNames[token.Text]; ok && token.Kind == lexer.IDENTIFIER {
+			builder.WriteString(code.text[last:token.Offset])
+			builder.WriteString(newName)
+			last = token.Offset + len(token.Text)
+		}
+	}
+	builder.WriteString(code.text[last:])
+	return builder.String(), true
+}
+
+func isNextToDot(tokens []lexer.Token, i int) bool {
+	return (i > 0 && tokens[i-1].Text == ".") || (i+1 < len(tokens) && tokens[i+1].Text == ".")
+}
+
+func isDunder(name string) bool {
+	return len(name) > 4 && strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__")
+}
+
+// matchCase capitalizes newName if name is capitalized, as it's exported in Go and a type or constant elsewhere
+func matchCase(name string, newName string) string {
+	first, _ := utf8.DecodeRuneInString(name)
+	if !unicode.IsUpper(first) {
+		return newName
+	}
+	if strings.ToUpper(name) == name && len(name) > 1 {
+		return strings.ToUpper(newName)
+	}
+	return strings.ToUpper(newName[:1]) + newName[1:]
+}
@@ -0,0 +1,89 @@
+package score_fusion
+
+import (
+	"math"
+	"math/rand"
+)
+
+const DEFAULT_HOLD_OUT_SHARE = 0.2
+const DEFAULT_SEED = 1
+const RELIABILITY_BINS = 10
+
+// ReliabilityBin is the pairs whose probability is in [Low, High), with the mean probability the model
+// gave them and the share of them that is plagiarised. A calibrated model has both about equal.
+type ReliabilityBin struct {
+	Low             float64
+	High            float64
+	Pairs           int
+	MeanProbability float64
+	PositiveShare   float64
+}
+
+// SplitHoldOut splits the examples into training and held-out examples, holding out about share of the
+// plagiarised and share of the original examples, the same for the same seed
+func SplitHoldOut(examples []Example, share float64, seed int64) ([]Example, []Example) {
+	random := rand.New(rand.NewSource(seed))
+	var training, heldOut []Example
+	for _, label := range []bool{true, false} {
+		var labeled []Example
+		for _, example := range examples {
+			if example.Label == label {
+				labeled = append(labeled, example)
+			}
+		}
+		random.Shuffle(len(labeled), func(i, j int) {
+			labeled[i], labeled[j] = labeled[j], labeled[i]
+		})
+		heldOutCount := int(math.Round(share * float64(len(labeled))))
+		heldOut = append(heldOut, labeled[:heldOutCount]...)
+		training = append(training, labeled[heldOutCount:]...)
+	}
+	return training, heldOut
+}
+
+// Brier is the mean squared difference of the probabilities of the examples and their labels, lower is
+// better. Unlike the log loss, a confident wrong probability costs at most 1.
+func (m *Model) Brier(examples []Example) float64 {
+	if len(examples) == 0 {
+		return 0
+	}
+	brier := 0.0
+	for _, example := range examples {
+		difference := m.Probability(example.Features)
+		if example.Label {
+			difference--
+		}
+		brier += difference * difference
+	}
+	return brier / float64(len(examples))
+}
+
+// Reliability groups the examples into RELIABILITY_BINS bins of equal width by their probability,
+// leaving out the empty bins
+func (m *Model) Reliability(examples []Example) []ReliabilityBin {
+	bins := make([]ReliabilityBin, RELIABILITY_BINS)
+	for i := range bins {
+		bins[i].Low = float64(i) / RELIABILITY_BINS
+		bins[i].High = float64(i+1) / RELIABILITY_BINS
+	}
+	for _, example := range examples {
+		probability := m.Probability(example.Features)
+		bin := &bins[int(math.Min(probability*RELIABILITY_BINS, RELIABILITY_BINS-1))]
+		bin.Pairs++
+		bin.MeanProbability += probability
+		if example.Label {
+			bin.PositiveShare++
+		}
+	}
+
+	var filledBins []ReliabilityBin
+	for _, bin := range bins {
+		if bin.Pairs == 0 {
+			continue
+		}
+		bin.MeanProbability /= float64(bin.Pairs)
+		bin.PositiveShare /= float64(bin.Pairs)
+		filledBins = append(filledBins, bin)
+	}
+	return filledBins
+}
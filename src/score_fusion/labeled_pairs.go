@@ -0,0 +1,81 @@
+package score_fusion
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LabeledPair is a pair of files, or of repo directories, known to be plagiarised or not
+type LabeledPair struct {
+	Path1       string
+	Path2       string
+	Label       bool
+	Obfuscation string // how the copy was disguised, e.g. "rename", if known
+}
+
+const LABEL_COPIED = "copied"
+const LABEL_NOT_COPIED = "not_copied"
+
+// ReadLabeledPairs reads a CSV file of labeled pairs with the header file1,file2,label and an optional
+// obfuscation column. The label is 1, true or copied for plagiarised pairs and 0, false or not_copied
+// otherwise. Relative paths are relative to the CSV file.
+func ReadLabeledPairs(path string) ([]LabeledPair, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	header := strings.Join(records[0], ",")
+	if header != "file1,file2,label" && header != "file1,file2,label,obfuscation" {
+		return nil, fmt.Errorf("%s must start with the header file1,file2,label or file1,file2,label,obfuscation", path)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(pairPath string) string {
+		if filepath.IsAbs(pairPath) {
+			return pairPath
+		}
+		return filepath.Join(dir, pairPath)
+	}
+	pairs := make([]LabeledPair, 0, len(records)-1)
+	for i, record := range records[1:] {
+		label, err := parseLabel(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+		}
+		pair := LabeledPair{Path1: resolve(record[0]), Path2: resolve(record[1]), Label: label}
+		if len(record) > 3 {
+			pair.Obfuscation = record[3]
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+func parseLabel(label string) (bool, error) {
+	switch strings.ToLower(label) {
+	case LABEL_COPIED:
+		return true, nil
+	case LABEL_NOT_COPIED:
+		return false, nil
+	}
+	parsedLabel, err := strconv.ParseBool(label)
+	if err != nil {
+		return false, fmt.Errorf("label %q must be 1, 0, true, false, %s or %s", label, LABEL_COPIED, LABEL_NOT_COPIED)
+	}
+	return parsedLabel, nil
+}
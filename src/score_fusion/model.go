@@ -0,0 +1,89 @@
+package score_fusion
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+const MODEL_VERSION = 1
+
+// The features of a pair of files the models are trained on
+const (
+	FEATURE_DAL                    = "dal"
+	FEATURE_CLNAT                  = "clnat"
+	FEATURE_WINNOWING              = "winnowing"
+	FEATURE_WINNOWING_MIN_COVERAGE = "winnowing_min_coverage" // the lower of the two coverages, high if both files are mostly shared
+	FEATURE_AST                    = "ast"                    // 0 if the language can't be parsed
+	FEATURE_HAS_AST                = "has_ast"                // 1 if the language can be parsed, so the AST weight only counts then
+	FEATURE_GST                    = "gst"
+	FEATURE_SEGMENT_COVERAGE       = "segment_coverage" // the higher share of significant lines in a copied segment
+	FEATURE_BASE_CODE_SHARE        = "base_code_share"
+	FEATURE_LOG_LINES              = "log_lines"  // log(1 + lines of the shorter file), short files are similar by chance
+	FEATURE_SIZE_RATIO             = "size_ratio" // length of the shorter file over the longer
+)
+
+var ALL_FEATURES = []string{
+	FEATURE_DAL, FEATURE_CLNAT, FEATURE_WINNOWING, FEATURE_WINNOWING_MIN_COVERAGE,
+	FEATURE_AST, FEATURE_HAS_AST, FEATURE_GST, FEATURE_SEGMENT_COVERAGE,
+	FEATURE_BASE_CODE_SHARE, FEATURE_LOG_LINES, FEATURE_SIZE_RATIO,
+}
+
+// Model is a logistic regression on standardized features, whose output is the probability that
+// a pair of files is plagiarised. It's saved as JSON, so the weights can be read and compared.
+type Model struct {
+	Version       int
+	Features      []string
+	Means         []float64 // of the features in the training pairs, subtracted before weighting
+	Scales        []float64 // standard deviations of the features, divided by before weighting
+	Weights       []float64
+	Bias          float64
+	TrainingPairs int
+}
+
+// Probability is the calibrated probability that the pair of files with the features is plagiarised.
+// Features the model doesn't know are ignored, and those it knows but are missing are taken as 0.
+func (m *Model) Probability(features map[string]float64) float64 {
+	z := m.Bias
+	for i, feature := range m.Features {
+		z += m.Weights[i] * (features[feature] - m.Means[i]) / m.Scales[i]
+	}
+	return sigmoid(z)
+}
+
+func (m *Model) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s is not a score fusion model: %w", path, err)
+	}
+	if m.Version != MODEL_VERSION {
+		return nil, fmt.Errorf("%s is a version %d score fusion model, expected version %d", path, m.Version, MODEL_VERSION)
+	}
+	if len(m.Means) != len(m.Features) || len(m.Scales) != len(m.Features) || len(m.Weights) != len(m.Features) {
+		return nil, fmt.Errorf("%s has %d features but %d means, %d scales and %d weights",
+			path, len(m.Features), len(m.Means), len(m.Scales), len(m.Weights))
+	}
+	for i, scale := range m.Scales {
+		if scale <= 0 {
+			return nil, fmt.Errorf("%s has a scale of %f for %s, must be positive", path, scale, m.Features[i])
+		}
+	}
+	return &m, nil
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
@@ -0,0 +1,143 @@
+package score_fusion
+
+import (
+	"fmt"
+	"math"
+)
+
+const DEFAULT_EPOCHS = 2000
+const DEFAULT_LEARNING_RATE = 0.5
+const DEFAULT_L2 = 0.001 // keeps the weights finite when a feature separates the training pairs
+
+const MIN_SCALE = 1e-6 // features that are constant in the training pairs aren't scaled up
+
+type Example struct {
+	Features map[string]float64
+	Label    bool // true if the pair is plagiarised
+}
+
+type TrainOptions struct {
+	Epochs       int
+	LearningRate float64
+	L2           float64
+	HoldOutShare float64 // of the examples held out of training to check the calibration on, see SplitHoldOut
+	Seed         int64   // of the hold-out split
+}
+
+func DefaultTrainOptions() TrainOptions {
+	return TrainOptions{
+		Epochs:       DEFAULT_EPOCHS,
+		LearningRate: DEFAULT_LEARNING_RATE,
+		L2:           DEFAULT_L2,
+		HoldOutShare: DEFAULT_HOLD_OUT_SHARE,
+		Seed:         DEFAULT_SEED,
+	}
+}
+
+// Train fits a model of the features to the examples by minimizing the log loss with full batch gradient
+// descent. As the log loss is minimized, the probabilities of the model are calibrated on pairs like the
+// examples, so the examples should have about the share of plagiarised pairs the model will see.
+func Train(examples []Example, features []string, options TrainOptions) (*Model, error) {
+	positives := 0
+	for _, example := range examples {
+		if example.Label {
+			positives++
+		}
+	}
+	if positives == 0 || positives == len(examples) {
+		return nil, fmt.Errorf("the %d training pairs must include both plagiarised and original pairs, found %d plagiarised",
+			len(examples), positives)
+	}
+
+	m := Model{
+		Version:       MODEL_VERSION,
+		Features:      features,
+		Means:         make([]float64, len(features)),
+		Scales:        make([]float64, len(features)),
+		Weights:       make([]float64, len(features)),
+		TrainingPairs: len(examples),
+	}
+
+	// standardize, so one learning rate suits every feature
+	x := make([][]float64, len(examples))
+	for i, example := range examples {
+		x[i] = make([]float64, len(features))
+		for j, feature := range features {
+			x[i][j] = example.Features[feature]
+			m.Means[j] += x[i][j] / float64(len(examples))
+		}
+	}
+	for j := range features {
+		variance := 0.0
+		for i := range x {
+			variance += (x[i][j] - m.Means[j]) * (x[i][j] - m.Means[j]) / float64(len(examples))
+		}
+		m.Scales[j] = math.Max(math.Sqrt(variance), MIN_SCALE)
+		for i := range x {
+			x[i][j] = (x[i][j] - m.Means[j]) / m.Scales[j]
+		}
+	}
+
+	// start from the base rate
+	baseRate := float64(positives) / float64(len(examples))
+	m.Bias = math.Log(baseRate / (1 - baseRate))
+
+	gradient := make([]float64, len(features))
+	for epoch := 0; epoch < options.Epochs; epoch++ {
+		for j := range gradient {
+			gradient[j] = options.L2 * m.Weights[j]
+		}
+		biasGradient := 0.0
+		for i, example := range examples {
+			z := m.Bias
+			for j := range features {
+				z += m.Weights[j] * x[i][j]
+			}
+			residual := sigmoid(z)
+			if example.Label {
+				residual--
+			}
+			residual /= float64(len(examples))
+			for j := range features {
+				gradient[j] += residual * x[i][j]
+			}
+			biasGradient += residual
+		}
+		for j := range features {
+			m.Weights[j] -= options.LearningRate * gradient[j]
+		}
+		m.Bias -= options.LearningRate * biasGradient
+	}
+	return &m, nil
+}
+
+// LogLoss is the mean negative log likelihood of the labels of the examples under the model, lower is better
+func (m *Model) LogLoss(examples []Example) float64 {
+	if len(examples) == 0 {
+		return 0
+	}
+	loss := 0.0
+	for _, example := range examples {
+		probability := math.Min(math.Max(m.Probability(example.Features), 1e-15), 1-1e-15)
+		if example.Label {
+			loss -= math.Log(probability)
+		} else {
+			loss -= math.Log(1 - probability)
+		}
+	}
+	return loss / float64(len(examples))
+}
+
+// Accuracy is the share of the examples labeled right with a probability threshold of 0.5
+func (m *Model) Accuracy(examples []Example) float64 {
+	if len(examples) == 0 {
+		return 0
+	}
+	correct := 0
+	for _, example := range examples {
+		if (m.Probability(example.Features) > 0.5) == example.Label {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(examples))
+}
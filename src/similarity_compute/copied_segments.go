@@ -0,0 +1,122 @@
+package similarity_compute
+
+import (
+	"hercules/src/code_parser"
+	"hercules/src/substring_finder"
+	"hercules/src/util"
+	"strings"
+	"unicode/utf8"
+)
+
+const MIN_COPIED_SEGMENT_LINES = 4 // significant lines, shorter runs are common idioms
+
+type CopiedSegment struct {
+	Percentage            float64 // DAL similarity of the two sides of the segment
+	Text1SubstringIndexes SubstringIndexesObject
+	Text2SubstringIndexes SubstringIndexesObject
+	Text1StartLine        int // 1-based lines in the original texts, the ends inclusive
+	Text1EndLine          int
+	Text2StartLine        int
+	Text2EndLine          int
+	MatchedLines          int
+}
+
+type CopiedSegmentsResults struct {
+	Segments      []CopiedSegment // best first
+	Text1Coverage float64         // share of text1's significant lines in a segment
+	Text2Coverage float64
+	Text1Length   int // bytes of the original text1 in a segment
+}
+
+// ComputeCopiedSegments finds every copied block of the two texts by aligning their parsed lines,
+// so a file with several copied blocks gets all of them, each with its own similarity
+func ComputeCopiedSegments(parsedCodeTextObject1 *code_parser.ParsedCodeTextObject,
+	parsedCodeTextObject2 *code_parser.ParsedCodeTextObject) *CopiedSegmentsResults {
+	lines1 := strings.Split(parsedCodeTextObject1.ParsedCodeText, "\n")
+	lines2 := strings.Split(parsedCodeTextObject2.ParsedCodeText, "\n")
+
+	alignedSegments := substring_finder.FindAlignedSegments(lines1, lines2, MIN_COPIED_SEGMENT_LINES)
+
+	copiedSegmentsResults := CopiedSegmentsResults{}
+	covered1 := make([]bool, len(lines1))
+	covered2 := make([]bool, len(lines2))
+	for _, alignedSegment := range alignedSegments {
+		parsedStart1, parsedEnd1 := parsedLineRange(parsedCodeTextObject1, alignedSegment.Text1StartLine, alignedSegment.Text1EndLine)
+		parsedStart2, parsedEnd2 := parsedLineRange(parsedCodeTextObject2, alignedSegment.Text2StartLine, alignedSegment.Text2EndLine)
+		segmentText1 := parsedCodeTextObject1.ParsedCodeText[parsedStart1:parsedEnd1]
+		segmentText2 := parsedCodeTextObject2.ParsedCodeText[parsedStart2:parsedEnd2]
+
+		copiedSegment := CopiedSegment{
+			Percentage: segmentSimilarity(segmentText1, segmentText2),
+			Text1SubstringIndexes: SubstringIndexesObject{
+				StartIndex: parsedCodeTextObject1.FindOriginalIndex(parsedStart1),
+				EndIndex:   parsedCodeTextObject1.FindOriginalIndex(parsedEnd1),
+			},
+			Text2SubstringIndexes: SubstringIndexesObject{
+				StartIndex: parsedCodeTextObject2.FindOriginalIndex(parsedStart2),
+				EndIndex:   parsedCodeTextObject2.FindOriginalIndex(parsedEnd2),
+			},
+			Text1StartLine: parsedCodeTextObject1.FindLineNumber(parsedStart1),
+			Text1EndLine:   parsedCodeTextObject1.FindLineNumber(parsedEnd1 - 1),
+			Text2StartLine: parsedCodeTextObject2.FindLineNumber(parsedStart2),
+			Text2EndLine:   parsedCodeTextObject2.FindLineNumber(parsedEnd2 - 1),
+			MatchedLines:   alignedSegment.MatchedLines,
+		}
+		copiedSegmentsResults.Segments = append(copiedSegmentsResults.Segments, copiedSegment)
+		copiedSegmentsResults.Text1Length += copiedSegment.Text1SubstringIndexes.EndIndex - copiedSegment.Text1SubstringIndexes.StartIndex
+
+		for i := alignedSegment.Text1StartLine; i < alignedSegment.Text1EndLine; i++ {
+			covered1[i] = true
+		}
+		for j := alignedSegment.Text2StartLine; j < alignedSegment.Text2EndLine; j++ {
+			covered2[j] = true
+		}
+	}
+	copiedSegmentsResults.Text1Coverage = significantLineCoverage(lines1, covered1)
+	copiedSegmentsResults.Text2Coverage = significantLineCoverage(lines2, covered2)
+	return &copiedSegmentsResults
+}
+
+// parsedLineRange returns the indexes in ParsedCodeText of the lines [startLine, endLine), without the last newline
+func parsedLineRange(parsedCodeTextObject *code_parser.ParsedCodeTextObject, startLine int, endLine int) (int, int) {
+	parsedCodeText := parsedCodeTextObject.ParsedCodeText
+	lineStarts := parsedCodeTextObject.ParsedLineStarts
+	lineEnd := len(parsedCodeText)
+	if endLine < len(lineStarts) {
+		lineEnd = lineStarts[endLine]
+	}
+	if lineEnd > 0 && parsedCodeText[lineEnd-1] == '\n' {
+		lineEnd--
+	}
+	return lineStarts[startLine], lineEnd
+}
+
+// segmentSimilarity is the DAL similarity of two aligned blocks
+func segmentSimilarity(text1 string, text2 string) float64 {
+	if len(text1) == 0 || len(text2) == 0 {
+		return 0
+	}
+	needle, haystack := text1, text2
+	needleLength, haystackLength := utf8.RuneCountInString(needle), utf8.RuneCountInString(haystack)
+	if needleLength > haystackLength {
+		needle, haystack = haystack, needle
+		needleLength, haystackLength = haystackLength, needleLength
+	}
+	distance, _ := substring_finder.ArgminLevenshtein(needle, haystack)
+	return 1 - float64(distance+haystackLength-needleLength)/float64(haystackLength)
+}
+
+func significantLineCoverage(lines []string, covered []bool) float64 {
+	significantLines := 0
+	coveredLines := 0
+	for i, line := range lines {
+		if !util.IsSignificantLine(line) {
+			continue
+		}
+		significantLines++
+		if covered[i] {
+			coveredLines++
+		}
+	}
+	return coverage(coveredLines, significantLines)
+}
@@ -0,0 +1,54 @@
+package similarity_compute
+
+import (
+	"hercules/src/code_parser"
+	"hercules/src/substring_finder"
+)
+
+const GST_MINIMUM_MATCH_LENGTH = 12 // in tokens, shorter runs like a for loop header are in every file
+
+type MatchedTile struct {
+	Length         int // in tokens
+	Text1StartLine int
+	Text1EndLine   int
+	Text2StartLine int
+	Text2EndLine   int
+}
+
+type GreedyStringTilingResults struct {
+	Percentage    float64 // 2 * tiled tokens / all tokens
+	Text1Coverage float64 // share of text1's tokens in a tile
+	Text2Coverage float64 // share of text2's tokens in a tile
+	MatchedTiles  []MatchedTile
+}
+
+// ComputeGreedyStringTilingSimilarity tiles the normalized token streams of the two texts (JPlag style),
+// so reordered functions and split up blocks are still counted in full
+func ComputeGreedyStringTilingSimilarity(parsedCodeTextObject1 *code_parser.ParsedCodeTextObject,
+	parsedCodeTextObject2 *code_parser.ParsedCodeTextObject, minimumMatchLength int) *GreedyStringTilingResults {
+	if minimumMatchLength <= 0 {
+		minimumMatchLength = GST_MINIMUM_MATCH_LENGTH
+	}
+	tokens1, lines1 := parsedCodeTextObject1.TokenStream()
+	tokens2, lines2 := parsedCodeTextObject2.TokenStream()
+
+	tilingResults := substring_finder.GreedyStringTiling(tokens1, tokens2, minimumMatchLength)
+
+	matchedTiles := make([]MatchedTile, 0, len(tilingResults.Tiles))
+	for _, tile := range tilingResults.Tiles {
+		matchedTiles = append(matchedTiles, MatchedTile{
+			Length:         tile.Length,
+			Text1StartLine: lines1[tile.Text1Start],
+			Text1EndLine:   lines1[tile.Text1Start+tile.Length-1],
+			Text2StartLine: lines2[tile.Text2Start],
+			Text2EndLine:   lines2[tile.Text2Start+tile.Length-1],
+		})
+	}
+
+	return &GreedyStringTilingResults{
+		Percentage:    tilingResults.Coverage,
+		Text1Coverage: tilingResults.Text1Coverage,
+		Text2Coverage: tilingResults.Text2Coverage,
+		MatchedTiles:  matchedTiles,
+	}
+}
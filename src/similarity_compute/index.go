@@ -14,6 +14,10 @@ type SimilarityResults struct {
 	Percentage            float64
 	Text1SubstringIndexes SubstringIndexesObject
 	Text2SubstringIndexes SubstringIndexesObject
+	Text1StartLine        int // 1-based lines of the substrings in the original texts, the ends inclusive, 0 if empty
+	Text1EndLine          int
+	Text2StartLine        int
+	Text2EndLine          int
 }
 
 func ComputeLevenSimilarity(parsedCodeTextObject1 *code_parser.ParsedCodeTextObject,
@@ -32,14 +36,16 @@ func ComputeLevenSimilarity(parsedCodeTextObject1 *code_parser.ParsedCodeTextObj
 		higherPercentage = findParsedSSResult2.Percentage
 	}
 
+	// the indexes of FindSubstring are in the haystack, so the substring
+	// of text1 comes from searching for text2 in text1 and vice versa
 	text1SubstringIndexes := SubstringIndexesObject{
-		StartIndex: parsedCodeTextObject1.FindOriginalIndex(findParsedSSResult1.StartIndex),
-		EndIndex:   parsedCodeTextObject1.FindOriginalIndex(findParsedSSResult1.EndIndex),
+		StartIndex: parsedCodeTextObject1.FindOriginalIndex(findParsedSSResult2.StartIndex),
+		EndIndex:   parsedCodeTextObject1.FindOriginalIndex(findParsedSSResult2.EndIndex),
 	}
 
 	text2SubstringIndexes := SubstringIndexesObject{
-		StartIndex: parsedCodeTextObject2.FindOriginalIndex(findParsedSSResult2.StartIndex),
-		EndIndex:   parsedCodeTextObject2.FindOriginalIndex(findParsedSSResult2.EndIndex),
+		StartIndex: parsedCodeTextObject2.FindOriginalIndex(findParsedSSResult1.StartIndex),
+		EndIndex:   parsedCodeTextObject2.FindOriginalIndex(findParsedSSResult1.EndIndex),
 	}
 
 	similarityResults := SimilarityResults{
@@ -47,5 +53,16 @@ func ComputeLevenSimilarity(parsedCodeTextObject1 *code_parser.ParsedCodeTextObj
 		Text1SubstringIndexes: text1SubstringIndexes,
 		Text2SubstringIndexes: text2SubstringIndexes,
 	}
+	similarityResults.Text1StartLine, similarityResults.Text1EndLine = substringLines(parsedCodeTextObject1, findParsedSSResult2.StartIndex, findParsedSSResult2.EndIndex)
+	similarityResults.Text2StartLine, similarityResults.Text2EndLine = substringLines(parsedCodeTextObject2, findParsedSSResult1.StartIndex, findParsedSSResult1.EndIndex)
 	return &similarityResults
 }
+
+// substringLines returns the 1-based lines in the original text of the parsed substring [startIndex, endIndex),
+// the end inclusive, or 0, 0 if it is empty
+func substringLines(parsedCodeTextObject *code_parser.ParsedCodeTextObject, startIndex int, endIndex int) (int, int) {
+	if endIndex <= startIndex {
+		return 0, 0
+	}
+	return parsedCodeTextObject.FindLineNumber(startIndex), parsedCodeTextObject.FindLineNumber(endIndex - 1)
+}
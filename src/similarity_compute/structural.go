@@ -0,0 +1,104 @@
+package similarity_compute
+
+import (
+	"hercules/src/syntax_tree"
+	"sort"
+)
+
+const MIN_MATCHED_SUBTREE_SIZE = 8 // smaller subtrees like a single call are in every file
+
+type MatchedSubtree struct {
+	Kind           string
+	Category       string // function, class, control flow or empty
+	Size           int
+	Text1StartLine int
+	Text1EndLine   int
+	Text2StartLine int
+	Text2EndLine   int
+}
+
+type StructuralResults struct {
+	Percentage      float64 // the higher of the two coverages
+	Text1Coverage   float64 // share of text1's syntax tree nodes in matched subtrees
+	Text2Coverage   float64
+	MatchedSubtrees []MatchedSubtree
+}
+
+// ComputeStructuralSimilarity parses both files and matches their subtrees by a hash of
+// their node kinds, so renaming identifiers, reformatting and reordering or extracting
+// functions and statements don't hide copied code. The largest subtrees are matched first.
+// Returns syntax_tree.ErrUnsupportedLanguage if either file can't be parsed.
+func ComputeStructuralSimilarity(text1 string, path1 string, text2 string, path2 string) (*StructuralResults, error) {
+	root1, err := syntax_tree.Parse(text1, path1)
+	if err != nil {
+		return nil, err
+	}
+	root2, err := syntax_tree.Parse(text2, path2)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates2 := make(map[uint64][]*syntax_tree.Node)
+	root2.Walk(func(node *syntax_tree.Node) {
+		if node.Size >= MIN_MATCHED_SUBTREE_SIZE {
+			candidates2[node.Hash] = append(candidates2[node.Hash], node)
+		}
+	})
+
+	var nodes1 []*syntax_tree.Node
+	root1.Walk(func(node *syntax_tree.Node) {
+		if node.Size >= MIN_MATCHED_SUBTREE_SIZE {
+			nodes1 = append(nodes1, node)
+		}
+	})
+	// largest first, so that only maximal subtrees are matched
+	sort.SliceStable(nodes1, func(i, j int) bool {
+		return nodes1[i].Size > nodes1[j].Size
+	})
+
+	matched1 := make(map[*syntax_tree.Node]bool)
+	matched2 := make(map[*syntax_tree.Node]bool)
+	markMatched := func(matched map[*syntax_tree.Node]bool, node *syntax_tree.Node) {
+		node.Walk(func(descendant *syntax_tree.Node) { matched[descendant] = true })
+	}
+
+	var matchedSubtrees []MatchedSubtree
+	matchedSize := 0
+	for _, node1 := range nodes1 {
+		if matched1[node1] {
+			continue // inside a larger matched subtree
+		}
+		for _, node2 := range candidates2[node1.Hash] {
+			if matched2[node2] {
+				continue
+			}
+			markMatched(matched1, node1)
+			markMatched(matched2, node2)
+			matchedSize += node1.Size
+			matchedSubtrees = append(matchedSubtrees, MatchedSubtree{
+				Kind:           node1.Kind,
+				Category:       node1.Category(),
+				Size:           node1.Size,
+				Text1StartLine: node1.StartLine,
+				Text1EndLine:   node1.EndLine,
+				Text2StartLine: node2.StartLine,
+				Text2EndLine:   node2.EndLine,
+			})
+			break
+		}
+	}
+	sort.SliceStable(matchedSubtrees, func(i, j int) bool {
+		return matchedSubtrees[i].Text1StartLine < matchedSubtrees[j].Text1StartLine
+	})
+
+	structuralResults := StructuralResults{
+		Text1Coverage:   coverage(matchedSize, root1.Size),
+		Text2Coverage:   coverage(matchedSize, root2.Size),
+		MatchedSubtrees: matchedSubtrees,
+	}
+	structuralResults.Percentage = structuralResults.Text1Coverage
+	if structuralResults.Text2Coverage > structuralResults.Percentage {
+		structuralResults.Percentage = structuralResults.Text2Coverage
+	}
+	return &structuralResults, nil
+}
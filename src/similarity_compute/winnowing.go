@@ -0,0 +1,148 @@
+package similarity_compute
+
+import (
+	"hercules/src/code_parser"
+	"unicode"
+)
+
+const WINNOWING_K = 15      // k-gram length, in characters without whitespace
+const WINNOWING_WINDOW = 20 // any match of at least WINNOWING_K+WINNOWING_WINDOW-1 characters is found
+
+const winnowingHashBase = 1099511628211
+
+type WinnowingFingerprint struct {
+	Hash          uint64
+	StartPosition int // index of the k-gram in ParsedCodeText
+	EndPosition   int // index of the last character of the k-gram in ParsedCodeText
+}
+
+type MatchedKGram struct {
+	Text1StartLine int
+	Text1EndLine   int
+	Text2StartLine int
+	Text2EndLine   int
+}
+
+type WinnowingResults struct {
+	Percentage    float64 // the higher of the two coverages
+	Text1Coverage float64 // share of text1's fingerprints that are also in text2
+	Text2Coverage float64 // share of text2's fingerprints that are also in text1
+	MatchedKGrams []MatchedKGram
+}
+
+// ComputeWinnowingSimilarity compares the winnowing fingerprints (Schleimer et al.) of the two texts.
+// Unlike DAL, it's linear in the length of the texts and finds every shared k-gram, not one substring.
+func ComputeWinnowingSimilarity(parsedCodeTextObject1 *code_parser.ParsedCodeTextObject,
+	parsedCodeTextObject2 *code_parser.ParsedCodeTextObject) *WinnowingResults {
+	fingerprints1 := Winnow(parsedCodeTextObject1.ParsedCodeText, WINNOWING_K, WINNOWING_WINDOW)
+	fingerprints2 := Winnow(parsedCodeTextObject2.ParsedCodeText, WINNOWING_K, WINNOWING_WINDOW)
+
+	fingerprintMap2 := make(map[uint64]WinnowingFingerprint, len(fingerprints2))
+	for _, fingerprint := range fingerprints2 {
+		if _, ok := fingerprintMap2[fingerprint.Hash]; !ok {
+			fingerprintMap2[fingerprint.Hash] = fingerprint
+		}
+	}
+	hashSet1 := make(map[uint64]bool, len(fingerprints1))
+	for _, fingerprint := range fingerprints1 {
+		hashSet1[fingerprint.Hash] = true
+	}
+
+	var matchedKGrams []MatchedKGram
+	matched1 := 0
+	for _, fingerprint1 := range fingerprints1 {
+		fingerprint2, ok := fingerprintMap2[fingerprint1.Hash]
+		if !ok {
+			continue
+		}
+		matched1++
+		matchedKGrams = append(matchedKGrams, MatchedKGram{
+			Text1StartLine: parsedCodeTextObject1.FindLineNumber(fingerprint1.StartPosition),
+			Text1EndLine:   parsedCodeTextObject1.FindLineNumber(fingerprint1.EndPosition),
+			Text2StartLine: parsedCodeTextObject2.FindLineNumber(fingerprint2.StartPosition),
+			Text2EndLine:   parsedCodeTextObject2.FindLineNumber(fingerprint2.EndPosition),
+		})
+	}
+	matched2 := 0
+	for _, fingerprint2 := range fingerprints2 {
+		if hashSet1[fingerprint2.Hash] {
+			matched2++
+		}
+	}
+
+	winnowingResults := WinnowingResults{
+		Text1Coverage: coverage(matched1, len(fingerprints1)),
+		Text2Coverage: coverage(matched2, len(fingerprints2)),
+		MatchedKGrams: matchedKGrams,
+	}
+	winnowingResults.Percentage = winnowingResults.Text1Coverage
+	if winnowingResults.Text2Coverage > winnowingResults.Percentage {
+		winnowingResults.Percentage = winnowingResults.Text2Coverage
+	}
+	return &winnowingResults
+}
+
+// Winnow hashes every k-gram of text with whitespace removed, and keeps the
+// minimum hash of every window of consecutive k-grams as the fingerprints
+func Winnow(text string, k int, window int) []WinnowingFingerprint {
+	// the characters without whitespace and their index in text
+	var chars []rune
+	var positions []int
+	for i, char := range text {
+		if !unicode.IsSpace(char) {
+			chars = append(chars, char)
+			positions = append(positions, i)
+		}
+	}
+	if len(chars) < k {
+		return nil
+	}
+
+	// rolling hash of every k-gram
+	highestPower := uint64(1)
+	for i := 1; i < k; i++ {
+		highestPower *= winnowingHashBase
+	}
+	hashes := make([]uint64, len(chars)-k+1)
+	var hash uint64
+	for i := 0; i < k; i++ {
+		hash = hash*winnowingHashBase + uint64(chars[i])
+	}
+	hashes[0] = hash
+	for i := 1; i < len(hashes); i++ {
+		hash = (hash-uint64(chars[i-1])*highestPower)*winnowingHashBase + uint64(chars[i+k-1])
+		hashes[i] = hash
+	}
+
+	var fingerprints []WinnowingFingerprint
+	lastSelected := -1
+	for windowStart := 0; windowStart+window <= len(hashes) || (windowStart == 0 && len(hashes) > 0); windowStart++ {
+		windowEnd := windowStart + window
+		if windowEnd > len(hashes) {
+			windowEnd = len(hashes)
+		}
+		// the rightmost minimum, so that a minimum is selected once for all windows it's in
+		minIndex := windowStart
+		for i := windowStart; i < windowEnd; i++ {
+			if hashes[i] <= hashes[minIndex] {
+				minIndex = i
+			}
+		}
+		if minIndex != lastSelected {
+			fingerprints = append(fingerprints, WinnowingFingerprint{
+				Hash:          hashes[minIndex],
+				StartPosition: positions[minIndex],
+				EndPosition:   positions[minIndex+k-1],
+			})
+			lastSelected = minIndex
+		}
+	}
+	return fingerprints
+}
+
+func coverage(matched int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(matched) / float64(total)
+}
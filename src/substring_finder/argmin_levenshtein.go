@@ -2,58 +2,143 @@ package substring_finder
 
 import (
 	"math"
+	"unicode/utf8"
 )
 
+const bandWidth = 64 // needle characters per bit-parallel band, one per bit of a uint64
+
 // Min3 finds the minimum of three integers
 func Min3(a, b, c int) int {
 	return int(math.Min(float64(a), math.Min(float64(b), float64(c))))
 }
 
 // Calculates the edit distance for substrings in a haystack,
-// then finds the index of the needle in the haystack that minimizes the edit distance
+// then finds the index of the needle in the haystack that minimizes the edit distance.
+// The distance counts runes, and the index is a byte offset in the haystack at the start of a rune.
+//
+// The needle is processed in bands of 64 characters with the bit-parallel algorithm of Myers,
+// in the block form of Hyyrö, sweeping the whole haystack for every band. Only the differences
+// along the bottom row of the last band are kept, so the memory is O(len(haystack)).
 func ArgminLevenshtein(needle string, haystack string) (int, int) {
+	needleSymbols, haystackSymbols, alphabetSize := toRuneSymbols(needle, haystack)
+	minValue, endIndex := argminLevenshteinSymbols(needleSymbols, haystackSymbols, alphabetSize)
+	return minValue, runeToByteIndex(haystack, endIndex)
+}
+
+func argminLevenshteinSymbols(needle []int, haystack []int, alphabetSize int) (int, int) {
 	lenNeedle := len(needle)
 	lenHaystack := len(haystack)
 
-	// Create a 2D slice to store the distances
-	dp := make([][]int, lenNeedle+1)
-	for i := range dp {
-		dp[i] = make([]int, lenHaystack+1)
-	}
+	// horizontalDeltas[j] is row[j+1] - row[j] of the bottom row of the last band, -1, 0 or +1.
+	// The first row is all zeros, as the match can start anywhere in the haystack
+	horizontalDeltas := make([]int8, lenHaystack)
 
-	// Initialize the base cases
-	for i := 0; i <= lenNeedle; i++ {
-		dp[i][0] = i
-	}
-	// Fill the first row with zeros as per the modification
-	for j := 0; j <= lenHaystack; j++ {
-		dp[0][j] = 0
-	}
+	peq := make([]uint64, alphabetSize)
+	for bandStart := 0; bandStart < lenNeedle; bandStart += bandWidth {
+		bandEnd := bandStart + bandWidth
+		if bandEnd > lenNeedle {
+			bandEnd = lenNeedle
+		}
+		width := bandEnd - bandStart
+		highBit := uint64(1) << (width - 1)
+
+		// peq[c] has bit i set if the i-th character of the band is c
+		for i := range peq {
+			peq[i] = 0
+		}
+		for i := bandStart; i < bandEnd; i++ {
+			peq[needle[i]] |= uint64(1) << (i - bandStart)
+		}
+
+		// the first column is 0, 1, 2, ..., so every vertical difference is +1
+		pv := ^uint64(0) >> (bandWidth - width)
+		mv := uint64(0)
+		for j := 0; j < lenHaystack; j++ {
+			eq := peq[haystack[j]]
+			horizontalIn := horizontalDeltas[j]
+
+			xv := eq | mv
+			if horizontalIn < 0 {
+				eq |= 1
+			}
+			xh := (((eq & pv) + pv) ^ pv) | eq
+			ph := mv | ^(xh | pv)
+			mh := pv & xh
+
+			var horizontalOut int8
+			if ph&highBit != 0 {
+				horizontalOut = 1
+			} else if mh&highBit != 0 {
+				horizontalOut = -1
+			}
 
-	// Fill the 2D slice with the distances
-	for i := 1; i <= lenNeedle; i++ {
-		for j := 1; j <= lenHaystack; j++ {
-			cost := 0
-			if needle[i-1] != haystack[j-1] {
-				cost = 1
+			ph <<= 1
+			mh <<= 1
+			if horizontalIn < 0 {
+				mh |= 1
+			} else if horizontalIn > 0 {
+				ph |= 1
 			}
-			dp[i][j] = Min3(
-				dp[i-1][j]+1,      // Deletion
-				dp[i][j-1]+1,      // Insertion
-				dp[i-1][j-1]+cost, // Substitution
-			)
+			pv = mh | ^(xv | ph)
+			mv = ph & xv
+
+			horizontalDeltas[j] = horizontalOut
 		}
 	}
 
 	// Find the smallest value in the last row
-	minValue := dp[lenNeedle][0]
+	value := lenNeedle
+	minValue := value
 	endIndex := 0
 	for j := 1; j <= lenHaystack; j++ {
-		if dp[lenNeedle][j] <= minValue { // we want the last index, so we need the equality
-			minValue = dp[lenNeedle][j]
+		value += int(horizontalDeltas[j-1])
+		if value <= minValue { // we want the last index, so we need the equality
+			minValue = value
 			endIndex = j
 		}
 	}
-
 	return minValue, endIndex
 }
+
+// toRuneSymbols numbers the distinct runes of both strings from 0, so the bit vectors of a band fit in a slice
+func toRuneSymbols(needle string, haystack string) ([]int, []int, int) {
+	symbolMap := make(map[rune]int)
+	convert := func(text string) []int {
+		symbols := make([]int, 0, len(text))
+		for _, char := range text {
+			symbol, ok := symbolMap[char]
+			if !ok {
+				symbol = len(symbolMap)
+				symbolMap[char] = symbol
+			}
+			symbols = append(symbols, symbol)
+		}
+		return symbols
+	}
+	needleSymbols := convert(needle)
+	haystackSymbols := convert(haystack)
+	return needleSymbols, haystackSymbols, len(symbolMap)
+}
+
+// runeToByteIndex returns the byte offset of the runeIndex-th rune of text
+func runeToByteIndex(text string, runeIndex int) int {
+	if runeIndex <= 0 {
+		return 0
+	}
+	count := 0
+	for i := range text {
+		if count == runeIndex {
+			return i
+		}
+		count++
+	}
+	return len(text)
+}
+
+// RuneCount is the number of runes of text[startIndex:endIndex], 0 for an empty or invalid range
+func RuneCount(text string, startIndex int, endIndex int) int {
+	if startIndex < 0 || endIndex > len(text) || startIndex >= endIndex {
+		return 0
+	}
+	return utf8.RuneCountInString(text[startIndex:endIndex])
+}
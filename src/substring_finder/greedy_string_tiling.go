@@ -0,0 +1,197 @@
+package substring_finder
+
+import (
+	"hercules/src/util"
+	"sort"
+)
+
+const GST_INITIAL_SEARCH_LENGTH = 20 // longer matches are found by growing the search length
+
+const gstHashBase = 1000003
+
+// MAX_GST_WINDOWS_PER_HASH caps the windows of text2 a window of text1 is checked against. Repetitive code
+// like a table of one number has a window per token with the same hash, which would be quadratic.
+const MAX_GST_WINDOWS_PER_HASH = 100
+
+// Tile is a maximal run of identical tokens, Text1Start and Text2Start are token indexes
+type Tile struct {
+	Text1Start int
+	Text2Start int
+	Length     int
+}
+
+type GreedyStringTilingResults struct {
+	Tiles         []Tile  // non-overlapping, sorted by Text1Start
+	Coverage      float64 // 2 * tiled tokens / (tokens1 + tokens2), as in JPlag
+	Text1Coverage float64 // share of tokens1 that is tiled
+	Text2Coverage float64
+}
+
+type gstMatch struct {
+	text1Start int
+	text2Start int
+	length     int
+}
+
+// GreedyStringTiling finds the non-overlapping tiles of at least minimumMatchLength tokens shared by
+// both token streams with Running-Karp-Rabin Greedy String Tiling (Wise), longest tiles first. Unlike
+// FindSubstring it finds every copied block, however the blocks were split up and reordered.
+func GreedyStringTiling(tokens1 []string, tokens2 []string, minimumMatchLength int) GreedyStringTilingResults {
+	if minimumMatchLength < 1 {
+		minimumMatchLength = 1
+	}
+	symbols1, symbols2 := toSymbols(tokens1, tokens2)
+	marked1 := make([]bool, len(symbols1))
+	marked2 := make([]bool, len(symbols2))
+
+	var tiles []Tile
+	tiledLength := 0
+	searchLength := util.Max(minimumMatchLength, GST_INITIAL_SEARCH_LENGTH)
+	for searchLength >= minimumMatchLength {
+		matches, maxMatch := scanPatterns(symbols1, symbols2, marked1, marked2, searchLength)
+		if maxMatch > 2*searchLength {
+			// long matches exist, so rescan for them before marking the short ones
+			searchLength = maxMatch
+			continue
+		}
+
+		// mark the matches, longest first, skipping the ones occluded by an earlier tile
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].length > matches[j].length
+		})
+		for _, match := range matches {
+			if isOccluded(marked1, match.text1Start, match.length) || isOccluded(marked2, match.text2Start, match.length) {
+				continue
+			}
+			for k := 0; k < match.length; k++ {
+				marked1[match.text1Start+k] = true
+				marked2[match.text2Start+k] = true
+			}
+			tiledLength += match.length
+			tiles = append(tiles, Tile{Text1Start: match.text1Start, Text2Start: match.text2Start, Length: match.length})
+		}
+
+		if searchLength > 2*minimumMatchLength {
+			searchLength /= 2
+		} else if searchLength > minimumMatchLength {
+			searchLength = minimumMatchLength
+		} else {
+			break
+		}
+	}
+
+	sort.Slice(tiles, func(i, j int) bool {
+		return tiles[i].Text1Start < tiles[j].Text1Start
+	})
+
+	results := GreedyStringTilingResults{Tiles: tiles}
+	if len(symbols1)+len(symbols2) > 0 {
+		results.Coverage = 2 * float64(tiledLength) / float64(len(symbols1)+len(symbols2))
+	}
+	if len(symbols1) > 0 {
+		results.Text1Coverage = float64(tiledLength) / float64(len(symbols1))
+	}
+	if len(symbols2) > 0 {
+		results.Text2Coverage = float64(tiledLength) / float64(len(symbols2))
+	}
+	return results
+}
+
+// scanPatterns hashes every unmarked window of searchLength tokens of text2, looks up the unmarked
+// windows of text1 and extends every verified hit as far as it goes. A hit that continues the hit of
+// the previous window of text1 on the same diagonal ends where it does, so it isn't extended again.
+func scanPatterns(symbols1 []int, symbols2 []int, marked1 []bool, marked2 []bool, searchLength int) ([]gstMatch, int) {
+	windows2 := make(map[uint64][]int)
+	forEachUnmarkedWindow(symbols2, marked2, searchLength, func(start int, hash uint64) {
+		if len(windows2[hash]) < MAX_GST_WINDOWS_PER_HASH {
+			windows2[hash] = append(windows2[hash], start)
+		}
+	})
+
+	type diagonalRun struct {
+		text1Start int // of the last hit on the diagonal
+		end        int // in text1, exclusive
+	}
+	diagonalRuns := make(map[int]diagonalRun) // by text1Start - text2Start
+
+	var matches []gstMatch
+	maxMatch := 0
+	forEachUnmarkedWindow(symbols1, marked1, searchLength, func(start1 int, hash uint64) {
+		for _, start2 := range windows2[hash] {
+			length := 0
+			if run, ok := diagonalRuns[start1-start2]; ok && run.text1Start == start1-1 && run.end > start1 {
+				length = run.end - start1
+			} else {
+				for start1+length < len(symbols1) && start2+length < len(symbols2) &&
+					symbols1[start1+length] == symbols2[start2+length] &&
+					!marked1[start1+length] && !marked2[start2+length] {
+					length++
+				}
+			}
+			if length < searchLength {
+				continue // hash collision
+			}
+			diagonalRuns[start1-start2] = diagonalRun{text1Start: start1, end: start1 + length}
+			matches = append(matches, gstMatch{text1Start: start1, text2Start: start2, length: length})
+			if length > maxMatch {
+				maxMatch = length
+			}
+		}
+	})
+	return matches, maxMatch
+}
+
+// forEachUnmarkedWindow calls f with the Karp-Rabin hash of every window of length tokens without a marked token
+func forEachUnmarkedWindow(symbols []int, marked []bool, length int, f func(start int, hash uint64)) {
+	if len(symbols) < length {
+		return
+	}
+	highestPower := uint64(1)
+	for i := 1; i < length; i++ {
+		highestPower *= gstHashBase
+	}
+
+	var hash uint64
+	unmarkedRun := 0 // number of unmarked tokens ending at i
+	for i := 0; i < len(symbols); i++ {
+		if i >= length {
+			hash -= uint64(symbols[i-length]) * highestPower
+		}
+		hash = hash*gstHashBase + uint64(symbols[i])
+		if marked[i] {
+			unmarkedRun = 0
+		} else {
+			unmarkedRun++
+		}
+		if i >= length-1 && unmarkedRun >= length {
+			f(i-length+1, hash)
+		}
+	}
+}
+
+func isOccluded(marked []bool, start int, length int) bool {
+	for k := 0; k < length; k++ {
+		if marked[start+k] {
+			return true
+		}
+	}
+	return false
+}
+
+// toSymbols maps the tokens of both streams to the same integers
+func toSymbols(tokens1 []string, tokens2 []string) ([]int, []int) {
+	symbolMap := make(map[string]int)
+	convert := func(tokens []string) []int {
+		symbols := make([]int, len(tokens))
+		for i, token := range tokens {
+			symbol, ok := symbolMap[token]
+			if !ok {
+				symbol = len(symbolMap) + 1
+				symbolMap[token] = symbol
+			}
+			symbols[i] = symbol
+		}
+		return symbols
+	}
+	return convert(tokens1), convert(tokens2)
+}
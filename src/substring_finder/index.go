@@ -12,11 +12,17 @@ func FindSubstring(needle string, haystack string) SubstringResults {
 	// FindSubstring finds the substring in a haystack that is most similar to the needle
 	minValue, endIndex := ArgminLevenshtein(needle, haystack)
 
+	// the reverse of the haystack has the same runes backwards, so a byte offset
+	// from its start is the same byte offset from the end of the haystack
 	_, tempIndex := ArgminLevenshtein(util.Reverse(needle), util.Reverse(haystack))
 	startIndex := len(haystack) - tempIndex
 
+	percentage := 0.0
+	if substringLength := RuneCount(haystack, startIndex, endIndex); substringLength > 0 {
+		percentage = 1 - (float64(minValue) / float64(substringLength))
+	}
 	substringResults := SubstringResults{
-		Percentage: 1 - (float64(minValue) / float64(endIndex-startIndex)),
+		Percentage: percentage,
 		StartIndex: startIndex,
 		EndIndex:   endIndex,
 	}
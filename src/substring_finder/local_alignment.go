@@ -0,0 +1,175 @@
+package substring_finder
+
+import (
+	"hercules/src/util"
+	"math"
+	"strings"
+)
+
+// scores of the line alignment
+const (
+	ALIGNMENT_MATCH         = 2
+	ALIGNMENT_TRIVIAL_MATCH = 0 // lines like "}" match everywhere, so they only connect significant lines
+	ALIGNMENT_MISMATCH      = -2
+	ALIGNMENT_GAP_OPEN      = -3
+	ALIGNMENT_GAP_EXTEND    = -1
+)
+
+const MAX_ALIGNED_SEGMENTS = 50
+
+const blockedCell = math.MinInt32 / 2 // can't be reached, and adding a penalty doesn't overflow
+
+// AlignedSegment is a run of lines of text1 aligned to a run of lines of text2,
+// with 0-based line indexes, the ends exclusive
+type AlignedSegment struct {
+	Text1StartLine int
+	Text1EndLine   int
+	Text2StartLine int
+	Text2EndLine   int
+	MatchedLines   int // significant lines that are the same in both
+	Score          int
+}
+
+type alignmentCell struct {
+	score        int
+	startLine1   int
+	startLine2   int
+	matchedLines int
+}
+
+// FindAlignedSegments finds every run of at least minimumLines significant lines that is in both texts,
+// best first. It's Smith-Waterman with affine gaps (Gotoh) over lines, repeated with the lines of the
+// found segments masked, so the segments don't overlap. Unlike FindSubstring, a file that is two
+// copied blocks with own code in between gives two segments, not one span over all of it.
+func FindAlignedSegments(lines1 []string, lines2 []string, minimumLines int) []AlignedSegment {
+	symbols1, symbols2 := toSymbols(trimLines(lines1), trimLines(lines2))
+	significant1 := significantLines(lines1)
+	significant2 := significantLines(lines2)
+	masked1 := make([]bool, len(lines1))
+	masked2 := make([]bool, len(lines2))
+
+	var segments []AlignedSegment
+	for len(segments) < MAX_ALIGNED_SEGMENTS {
+		segment, found := alignBestSegment(symbols1, symbols2, significant1, significant2, masked1, masked2)
+		if !found || segment.MatchedLines < minimumLines {
+			break
+		}
+		segments = append(segments, segment)
+		for i := segment.Text1StartLine; i < segment.Text1EndLine; i++ {
+			masked1[i] = true
+		}
+		for j := segment.Text2StartLine; j < segment.Text2EndLine; j++ {
+			masked2[j] = true
+		}
+	}
+	return segments
+}
+
+// alignBestSegment is one pass of Smith-Waterman-Gotoh, keeping one row of each table and
+// carrying the start of the alignment along instead of tracing it back
+func alignBestSegment(
+	symbols1 []int, symbols2 []int,
+	significant1 []bool, significant2 []bool,
+	masked1 []bool, masked2 []bool,
+) (AlignedSegment, bool) {
+	// the best alignment ending at line i of text1 and line j of text2, in the previous and the current row,
+	// and the best ending in a gap in text2, the one ending in a gap in text1 is horizontalGap
+	previousRow := make([]alignmentCell, len(symbols2)+1)
+	currentRow := make([]alignmentCell, len(symbols2)+1)
+	verticalGaps := make([]alignmentCell, len(symbols2)+1)
+	for j := range verticalGaps {
+		verticalGaps[j].score = blockedCell
+	}
+
+	var best AlignedSegment
+	found := false
+	for i := 1; i <= len(symbols1); i++ {
+		horizontalGap := alignmentCell{score: blockedCell}
+		currentRow[0] = alignmentCell{}
+		for j := 1; j <= len(symbols2); j++ {
+			if masked1[i-1] || masked2[j-1] {
+				currentRow[j] = alignmentCell{}
+				horizontalGap = alignmentCell{score: blockedCell}
+				verticalGaps[j] = alignmentCell{score: blockedCell}
+				continue
+			}
+
+			horizontalGap = bestGap(horizontalGap, currentRow[j-1])
+			verticalGaps[j] = bestGap(verticalGaps[j], previousRow[j])
+
+			diagonal := previousRow[j-1]
+			if diagonal.score == 0 {
+				// start a new alignment here
+				diagonal = alignmentCell{startLine1: i - 1, startLine2: j - 1}
+			}
+			isSignificant := significant1[i-1] && significant2[j-1]
+			if symbols1[i-1] == symbols2[j-1] {
+				if isSignificant {
+					diagonal.score += ALIGNMENT_MATCH
+					diagonal.matchedLines++
+				} else {
+					diagonal.score += ALIGNMENT_TRIVIAL_MATCH
+				}
+			} else {
+				diagonal.score += ALIGNMENT_MISMATCH
+			}
+
+			cell := diagonal
+			if horizontalGap.score > cell.score {
+				cell = horizontalGap
+			}
+			if verticalGaps[j].score > cell.score {
+				cell = verticalGaps[j]
+			}
+			if cell.score <= 0 {
+				cell = alignmentCell{}
+			}
+			currentRow[j] = cell
+
+			if cell.score > best.Score {
+				best = AlignedSegment{
+					Text1StartLine: cell.startLine1,
+					Text1EndLine:   i,
+					Text2StartLine: cell.startLine2,
+					Text2EndLine:   j,
+					MatchedLines:   cell.matchedLines,
+					Score:          cell.score,
+				}
+				found = true
+			}
+		}
+		previousRow, currentRow = currentRow, previousRow
+	}
+	return best, found
+}
+
+// bestGap opens a gap after cell or extends gap, whichever scores higher
+func bestGap(gap alignmentCell, cell alignmentCell) alignmentCell {
+	extended := gap
+	extended.score += ALIGNMENT_GAP_EXTEND
+	opened := cell
+	opened.score += ALIGNMENT_GAP_OPEN
+	if cell.score > 0 && opened.score >= extended.score {
+		return opened
+	}
+	if gap.score == blockedCell {
+		return gap
+	}
+	return extended
+}
+
+func trimLines(lines []string) []string {
+	trimmedLines := make([]string, len(lines))
+	for i, line := range lines {
+		trimmedLines[i] = strings.TrimSpace(line)
+	}
+	return trimmedLines
+}
+
+func significantLines(lines []string) []bool {
+	significant := make([]bool, len(lines))
+	for i, line := range lines {
+		significant[i] = util.IsSignificantLine(line)
+	}
+	return significant
+}
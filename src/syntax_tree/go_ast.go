@@ -0,0 +1,64 @@
+package syntax_tree
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+func parseGo(text string) (*Node, error) {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "", text, parser.SkipObjectResolution)
+	if file == nil {
+		return nil, err
+	}
+	// a partial tree is still worth comparing, so syntax errors are ignored
+
+	var root *Node
+	var stack []*Node
+	ast.Inspect(file, func(astNode ast.Node) bool {
+		if astNode == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		switch astNode.(type) {
+		case *ast.CommentGroup, *ast.Comment:
+			return false
+		}
+
+		node := &Node{
+			Kind:      goKind(astNode),
+			StartLine: fileSet.Position(astNode.Pos()).Line,
+			EndLine:   fileSet.Position(astNode.End()).Line,
+		}
+		if len(stack) == 0 {
+			root = node
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+		return true
+	})
+	return root, nil
+}
+
+// goKind is the type of the node, with the operator for expressions so that a+b and a-b differ
+func goKind(astNode ast.Node) string {
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", astNode), "*ast.")
+	switch typedNode := astNode.(type) {
+	case *ast.BinaryExpr:
+		kind += typedNode.Op.String()
+	case *ast.UnaryExpr:
+		kind += typedNode.Op.String()
+	case *ast.AssignStmt:
+		kind += typedNode.Tok.String()
+	case *ast.IncDecStmt:
+		kind += typedNode.Tok.String()
+	case *ast.BranchStmt:
+		kind += typedNode.Tok.String()
+	}
+	return kind
+}
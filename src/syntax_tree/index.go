@@ -0,0 +1,112 @@
+package syntax_tree
+
+import (
+	"errors"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnsupportedLanguage = errors.New("no parser for this language")
+
+const (
+	CATEGORY_FUNCTION     = "function"
+	CATEGORY_CLASS        = "class"
+	CATEGORY_CONTROL_FLOW = "control flow"
+)
+
+// Node is a language independent syntax tree node. Identifiers and literals are
+// only kept by their kind, so renaming them doesn't change the Hash of a subtree.
+type Node struct {
+	Kind      string
+	StartLine int // 1-based
+	EndLine   int
+	Children  []*Node
+	Hash      uint64 // of the kinds in the subtree
+	Size      int    // number of nodes in the subtree
+}
+
+// Parse parses text with go/ast for Go files and with tree-sitter for other languages
+func Parse(text string, path string) (*Node, error) {
+	extension := strings.ToLower(filepath.Ext(path))
+	var root *Node
+	var err error
+	if extension == ".go" {
+		root, err = parseGo(text)
+	} else {
+		root, err = parseTreeSitter(text, extension)
+	}
+	if err != nil {
+		return nil, err
+	}
+	root.computeHashes()
+	return root, nil
+}
+
+// IsSupported is true if Parse has a parser for the file's language
+func IsSupported(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	_, ok := TREE_SITTER_LANGUAGES[extension]
+	return ok || extension == ".go"
+}
+
+func (node *Node) computeHashes() {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(node.Kind))
+	node.Size = 1
+	for _, child := range node.Children {
+		child.computeHashes()
+		node.Size += child.Size
+		var childHash [8]byte
+		for i := range childHash {
+			childHash[i] = byte(child.Hash >> (8 * i))
+		}
+		hasher.Write(childHash[:])
+	}
+	node.Hash = hasher.Sum64()
+}
+
+// Walk calls f for node and all its descendants, parents first
+func (node *Node) Walk(f func(*Node)) {
+	f(node)
+	for _, child := range node.Children {
+		child.Walk(f)
+	}
+}
+
+var FUNCTION_KINDS = []string{
+	"FuncDecl", "FuncLit",
+	"function_declaration", "function_definition", "method_declaration", "method_definition",
+	"function_expression", "arrow_function", "constructor_declaration", "lambda_expression", "generator_function_declaration",
+}
+
+var CLASS_KINDS = []string{
+	"TypeSpec",
+	"class_declaration", "class_definition", "class_specifier", "struct_specifier", "interface_declaration",
+	"enum_declaration", "record_declaration", "class",
+}
+
+var CONTROL_FLOW_KINDS = []string{
+	"IfStmt", "ForStmt", "RangeStmt", "SwitchStmt", "TypeSwitchStmt", "SelectStmt",
+	"if_statement", "for_statement", "for_in_statement", "for_range_loop", "enhanced_for_statement",
+	"while_statement", "do_statement", "switch_statement", "switch_expression", "try_statement",
+	"match_statement", "with_statement",
+}
+
+func (node *Node) Category() string {
+	for _, kinds := range []struct {
+		category string
+		kinds    []string
+	}{
+		{CATEGORY_FUNCTION, FUNCTION_KINDS},
+		{CATEGORY_CLASS, CLASS_KINDS},
+		{CATEGORY_CONTROL_FLOW, CONTROL_FLOW_KINDS},
+	} {
+		for _, kind := range kinds.kinds {
+			if node.Kind == kind {
+				return kinds.category
+			}
+		}
+	}
+	return ""
+}
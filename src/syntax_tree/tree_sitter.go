@@ -0,0 +1,68 @@
+package syntax_tree
+
+import (
+	"context"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+var TREE_SITTER_LANGUAGES = map[string]func() *sitter.Language{
+	".py":   python.GetLanguage,
+	".java": java.GetLanguage,
+	".js":   javascript.GetLanguage,
+	".jsx":  javascript.GetLanguage,
+	".mjs":  javascript.GetLanguage,
+	".cjs":  javascript.GetLanguage,
+	".ts":   typescript.GetLanguage,
+	".tsx":  tsx.GetLanguage,
+	".c":    c.GetLanguage,
+	".h":    c.GetLanguage,
+	".cc":   cpp.GetLanguage,
+	".cpp":  cpp.GetLanguage,
+	".cxx":  cpp.GetLanguage,
+	".hpp":  cpp.GetLanguage,
+	".hh":   cpp.GetLanguage,
+	".hxx":  cpp.GetLanguage,
+}
+
+func parseTreeSitter(text string, extension string) (*Node, error) {
+	getLanguage, ok := TREE_SITTER_LANGUAGES[extension]
+	if !ok {
+		return nil, ErrUnsupportedLanguage
+	}
+	sitterRoot, err := sitter.ParseCtx(context.Background(), []byte(text), getLanguage())
+	if err != nil {
+		return nil, err
+	}
+	return convertSitterNode(sitterRoot), nil
+}
+
+// convertSitterNode keeps the named nodes only, the others are punctuation
+func convertSitterNode(sitterNode *sitter.Node) *Node {
+	kind := sitterNode.Type()
+	// operators are unnamed nodes, but a+b and a-b should differ
+	if operator := sitterNode.ChildByFieldName("operator"); operator != nil {
+		kind += operator.Type()
+	}
+	node := &Node{
+		Kind:      kind,
+		StartLine: int(sitterNode.StartPoint().Row) + 1,
+		EndLine:   int(sitterNode.EndPoint().Row) + 1,
+	}
+	for i := 0; i < int(sitterNode.NamedChildCount()); i++ {
+		child := sitterNode.NamedChild(i)
+		if strings.Contains(child.Type(), "comment") {
+			continue
+		}
+		node.Children = append(node.Children, convertSitterNode(child))
+	}
+	return node
+}
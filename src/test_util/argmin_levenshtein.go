@@ -0,0 +1,114 @@
+package test_util
+
+import (
+	"fmt"
+	"hercules/src/substring_finder"
+	"math/rand"
+)
+
+// ReferenceArgminLevenshtein is the original full matrix ArgminLevenshtein, kept to check the bit-parallel one against.
+// Like it, it compares runes and returns a byte offset.
+func ReferenceArgminLevenshtein(needleText string, haystackText string) (int, int) {
+	needle := []rune(needleText)
+	haystack := []rune(haystackText)
+	lenNeedle := len(needle)
+	lenHaystack := len(haystack)
+
+	// Create a 2D slice to store the distances
+	dp := make([][]int, lenNeedle+1)
+	for i := range dp {
+		dp[i] = make([]int, lenHaystack+1)
+	}
+
+	// Initialize the base cases
+	for i := 0; i <= lenNeedle; i++ {
+		dp[i][0] = i
+	}
+	// Fill the first row with zeros as per the modification
+	for j := 0; j <= lenHaystack; j++ {
+		dp[0][j] = 0
+	}
+
+	// Fill the 2D slice with the distances
+	for i := 1; i <= lenNeedle; i++ {
+		for j := 1; j <= lenHaystack; j++ {
+			cost := 0
+			if needle[i-1] != haystack[j-1] {
+				cost = 1
+			}
+			dp[i][j] = substring_finder.Min3(
+				dp[i-1][j]+1,      // Deletion
+				dp[i][j-1]+1,      // Insertion
+				dp[i-1][j-1]+cost, // Substitution
+			)
+		}
+	}
+
+	// Find the smallest value in the last row
+	minValue := dp[lenNeedle][0]
+	endIndex := 0
+	for j := 1; j <= lenHaystack; j++ {
+		if dp[lenNeedle][j] <= minValue { // we want the last index, so we need the equality
+			minValue = dp[lenNeedle][j]
+			endIndex = j
+		}
+	}
+
+	return minValue, len(string(haystack[:endIndex]))
+}
+
+// TestArgminLevenshtein compares ArgminLevenshtein with ReferenceArgminLevenshtein on random strings,
+// with needles around the 64 character band boundaries. It returns the first difference found.
+func TestArgminLevenshtein(iterations int, seed int64) error {
+	random := rand.New(rand.NewSource(seed))
+	alphabets := []string{"ab", "acgt", "abcdefghijklmnopqrstuvwxyz {}();\n", "aé中文😀 \n"}
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		alphabet := alphabets[iteration%len(alphabets)]
+		haystack := randomString(random, alphabet, random.Intn(300))
+		needle := randomString(random, alphabet, random.Intn(200))
+		if len(haystack) > 0 && random.Intn(2) == 0 {
+			// a mutated substring of the haystack, so there is a close match
+			haystackRunes := []rune(haystack)
+			start := random.Intn(len(haystackRunes))
+			end := start + random.Intn(len(haystackRunes)-start+1)
+			needle = mutate(random, alphabet, string(haystackRunes[start:end]))
+		}
+
+		expectedMinValue, expectedEndIndex := ReferenceArgminLevenshtein(needle, haystack)
+		minValue, endIndex := substring_finder.ArgminLevenshtein(needle, haystack)
+		if minValue != expectedMinValue || endIndex != expectedEndIndex {
+			return fmt.Errorf("ArgminLevenshtein(%q, %q) = (%d, %d), expected (%d, %d)",
+				needle, haystack, minValue, endIndex, expectedMinValue, expectedEndIndex)
+		}
+
+	}
+	return nil
+}
+
+func randomString(random *rand.Rand, alphabet string, length int) string {
+	chars := []rune(alphabet)
+	runes := make([]rune, length)
+	for i := range runes {
+		runes[i] = chars[random.Intn(len(chars))]
+	}
+	return string(runes)
+}
+
+// mutate substitutes, inserts or deletes about one in ten characters
+func mutate(random *rand.Rand, alphabet string, text string) string {
+	chars := []rune(alphabet)
+	var runes []rune
+	for _, char := range text {
+		switch random.Intn(30) {
+		case 0:
+			runes = append(runes, chars[random.Intn(len(chars))])
+		case 1:
+			runes = append(runes, char, chars[random.Intn(len(chars))])
+		case 2:
+		default:
+			runes = append(runes, char)
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,9 @@
+package test_util
+
+import "testing"
+
+func TestArgminLevenshteinAgainstReference(t *testing.T) {
+	if err := TestArgminLevenshtein(20000, 1); err != nil {
+		t.Fatal(err)
+	}
+}
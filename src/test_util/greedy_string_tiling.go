@@ -0,0 +1,248 @@
+package test_util
+
+import (
+	"fmt"
+	"hercules/src/code_parser"
+	"hercules/src/similarity_compute"
+	"hercules/src/substring_finder"
+	"hercules/src/util"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const GST_TABLE_ENTRIES = 3000
+const GST_TABLE_MAX_DURATION = 2 * time.Second
+
+const referenceGSTHashBase = 1000003
+
+type referenceGSTMatch struct {
+	text1Start int
+	text2Start int
+	length     int
+}
+
+// ReferenceGreedyStringTiling is the original GreedyStringTiling, which extends every window of text1 from
+// scratch against every window of text2 with the same hash, kept to check the faster one against.
+func ReferenceGreedyStringTiling(tokens1 []string, tokens2 []string, minimumMatchLength int) substring_finder.GreedyStringTilingResults {
+	if minimumMatchLength < 1 {
+		minimumMatchLength = 1
+	}
+	symbols1, symbols2 := referenceToSymbols(tokens1, tokens2)
+	marked1 := make([]bool, len(symbols1))
+	marked2 := make([]bool, len(symbols2))
+
+	var tiles []substring_finder.Tile
+	tiledLength := 0
+	searchLength := util.Max(minimumMatchLength, substring_finder.GST_INITIAL_SEARCH_LENGTH)
+	for searchLength >= minimumMatchLength {
+		matches, maxMatch := referenceScanPatterns(symbols1, symbols2, marked1, marked2, searchLength)
+		if maxMatch > 2*searchLength {
+			// long matches exist, so rescan for them before marking the short ones
+			searchLength = maxMatch
+			continue
+		}
+
+		// mark the matches, longest first, skipping the ones occluded by an earlier tile
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].length > matches[j].length
+		})
+		for _, match := range matches {
+			if referenceIsOccluded(marked1, match.text1Start, match.length) || referenceIsOccluded(marked2, match.text2Start, match.length) {
+				continue
+			}
+			for k := 0; k < match.length; k++ {
+				marked1[match.text1Start+k] = true
+				marked2[match.text2Start+k] = true
+			}
+			tiledLength += match.length
+			tiles = append(tiles, substring_finder.Tile{Text1Start: match.text1Start, Text2Start: match.text2Start, Length: match.length})
+		}
+
+		if searchLength > 2*minimumMatchLength {
+			searchLength /= 2
+		} else if searchLength > minimumMatchLength {
+			searchLength = minimumMatchLength
+		} else {
+			break
+		}
+	}
+
+	sort.Slice(tiles, func(i, j int) bool {
+		return tiles[i].Text1Start < tiles[j].Text1Start
+	})
+
+	results := substring_finder.GreedyStringTilingResults{Tiles: tiles}
+	if len(symbols1)+len(symbols2) > 0 {
+		results.Coverage = 2 * float64(tiledLength) / float64(len(symbols1)+len(symbols2))
+	}
+	if len(symbols1) > 0 {
+		results.Text1Coverage = float64(tiledLength) / float64(len(symbols1))
+	}
+	if len(symbols2) > 0 {
+		results.Text2Coverage = float64(tiledLength) / float64(len(symbols2))
+	}
+	return results
+}
+
+// referenceScanPatterns hashes every unmarked window of searchLength tokens of text2, looks up the unmarked
+// windows of text1 and extends every verified hit as far as it goes
+func referenceScanPatterns(symbols1 []int, symbols2 []int, marked1 []bool, marked2 []bool, searchLength int) ([]referenceGSTMatch, int) {
+	windows2 := make(map[uint64][]int)
+	referenceForEachUnmarkedWindow(symbols2, marked2, searchLength, func(start int, hash uint64) {
+		windows2[hash] = append(windows2[hash], start)
+	})
+
+	var matches []referenceGSTMatch
+	maxMatch := 0
+	referenceForEachUnmarkedWindow(symbols1, marked1, searchLength, func(start1 int, hash uint64) {
+		for _, start2 := range windows2[hash] {
+			length := 0
+			for start1+length < len(symbols1) && start2+length < len(symbols2) &&
+				symbols1[start1+length] == symbols2[start2+length] &&
+				!marked1[start1+length] && !marked2[start2+length] {
+				length++
+			}
+			if length < searchLength {
+				continue // hash collision
+			}
+			matches = append(matches, referenceGSTMatch{text1Start: start1, text2Start: start2, length: length})
+			if length > maxMatch {
+				maxMatch = length
+			}
+		}
+	})
+	return matches, maxMatch
+}
+
+// referenceForEachUnmarkedWindow calls f with the Karp-Rabin hash of every window of length tokens without a marked token
+func referenceForEachUnmarkedWindow(symbols []int, marked []bool, length int, f func(start int, hash uint64)) {
+	if len(symbols) < length {
+		return
+	}
+	highestPower := uint64(1)
+	for i := 1; i < length; i++ {
+		highestPower *= referenceGSTHashBase
+	}
+
+	var hash uint64
+	unmarkedRun := 0 // number of unmarked tokens ending at i
+	for i := 0; i < len(symbols); i++ {
+		if i >= length {
+			hash -= uint64(symbols[i-length]) * highestPower
+		}
+		hash = hash*referenceGSTHashBase + uint64(symbols[i])
+		if marked[i] {
+			unmarkedRun = 0
+		} else {
+			unmarkedRun++
+		}
+		if i >= length-1 && unmarkedRun >= length {
+			f(i-length+1, hash)
+		}
+	}
+}
+
+func referenceIsOccluded(marked []bool, start int, length int) bool {
+	for k := 0; k < length; k++ {
+		if marked[start+k] {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceToSymbols maps the tokens of both streams to the same integers
+func referenceToSymbols(tokens1 []string, tokens2 []string) ([]int, []int) {
+	symbolMap := make(map[string]int)
+	convert := func(tokens []string) []int {
+		symbols := make([]int, len(tokens))
+		for i, token := range tokens {
+			symbol, ok := symbolMap[token]
+			if !ok {
+				symbol = len(symbolMap) + 1
+				symbolMap[token] = symbol
+			}
+			symbols[i] = symbol
+		}
+		return symbols
+	}
+	return convert(tokens1), convert(tokens2)
+}
+
+// TestGreedyStringTiling compares GreedyStringTiling with ReferenceGreedyStringTiling on random token
+// streams of small alphabets, the second one made of mutated and reordered pieces of the first half
+// the time. It returns the first difference found.
+func TestGreedyStringTiling(iterations int, seed int64) error {
+	random := rand.New(rand.NewSource(seed))
+	alphabets := [][]string{{"a", "b"}, {"ID", "=", "LIT", ";"}, {"if", "(", ")", "{", "}", "ID", "return", "+"}}
+
+	for iteration := 0; iteration < iterations; iteration++ {
+		alphabet := alphabets[iteration%len(alphabets)]
+		tokens1 := randomTokens(random, alphabet, random.Intn(200))
+		tokens2 := randomTokens(random, alphabet, random.Intn(200))
+		if len(tokens1) > 0 && random.Intn(2) == 0 {
+			tokens2 = nil
+			for piece := 0; piece < 1+random.Intn(4); piece++ {
+				start := random.Intn(len(tokens1))
+				end := start + random.Intn(len(tokens1)-start+1)
+				tokens2 = append(tokens2, mutateTokens(random, alphabet, tokens1[start:end])...)
+				tokens2 = append(tokens2, randomTokens(random, alphabet, random.Intn(10))...)
+			}
+		}
+		minimumMatchLength := 3 + random.Intn(8)
+
+		expected := ReferenceGreedyStringTiling(tokens1, tokens2, minimumMatchLength)
+		results := substring_finder.GreedyStringTiling(tokens1, tokens2, minimumMatchLength)
+		if !reflect.DeepEqual(results, expected) {
+			return fmt.Errorf("GreedyStringTiling(%q, %q, %d) = %+v, expected %+v",
+				tokens1, tokens2, minimumMatchLength, results, expected)
+		}
+	}
+	return nil
+}
+
+// TestGreedyStringTilingOnTable compares a Go file of a table of GST_TABLE_ENTRIES times the same number
+// with itself, which every window of has the same hash, and returns an error if it takes longer than
+// GST_TABLE_MAX_DURATION or doesn't tile all of it
+func TestGreedyStringTilingOnTable() error {
+	text := "package table\n\nvar TABLE = []int{" + strings.Repeat("1, ", GST_TABLE_ENTRIES) + "}\n"
+	tokens, _ := code_parser.ParseCode(text, "table.go").TokenStream()
+
+	start := time.Now()
+	results := substring_finder.GreedyStringTiling(tokens, tokens, similarity_compute.GST_MINIMUM_MATCH_LENGTH)
+	if duration := time.Since(start); duration > GST_TABLE_MAX_DURATION {
+		return fmt.Errorf("GreedyStringTiling of a table of %d entries took %v", GST_TABLE_ENTRIES, duration)
+	}
+	if results.Coverage != 1 {
+		return fmt.Errorf("GreedyStringTiling of a table of %d entries with itself covered %f", GST_TABLE_ENTRIES, results.Coverage)
+	}
+	return nil
+}
+
+func randomTokens(random *rand.Rand, alphabet []string, length int) []string {
+	tokens := make([]string, length)
+	for i := range tokens {
+		tokens[i] = alphabet[random.Intn(len(alphabet))]
+	}
+	return tokens
+}
+
+// mutateTokens substitutes, inserts or deletes about one in ten tokens
+func mutateTokens(random *rand.Rand, alphabet []string, tokens []string) []string {
+	var mutated []string
+	for _, token := range tokens {
+		switch random.Intn(30) {
+		case 0:
+			mutated = append(mutated, alphabet[random.Intn(len(alphabet))])
+		case 1:
+			mutated = append(mutated, token, alphabet[random.Intn(len(alphabet))])
+		case 2:
+		default:
+			mutated = append(mutated, token)
+		}
+	}
+	return mutated
+}
@@ -0,0 +1,15 @@
+package test_util
+
+import "testing"
+
+func TestGreedyStringTilingMatchesReference(t *testing.T) {
+	if err := TestGreedyStringTiling(5000, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGreedyStringTilingOnRepetitiveTable(t *testing.T) {
+	if err := TestGreedyStringTilingOnTable(); err != nil {
+		t.Fatal(err)
+	}
+}
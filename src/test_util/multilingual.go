@@ -0,0 +1,101 @@
+package test_util
+
+import (
+	"fmt"
+	"hercules/src/code_parser"
+	"hercules/src/similarity_compute"
+	"strings"
+	"unicode/utf8"
+)
+
+type multilingualSample struct {
+	path string
+	text string
+}
+
+// source with non-ASCII comments, identifiers, strings and digits, and a last line without a newline
+var MULTILINGUAL_SAMPLES = []multilingualSample{
+	{"sample.go", "package main\n\n// 计算总和\nfunc 总和(数字 []int) int {\n\tσ := 0\n\tfor _, n := range 数字 {\n\t\tσ += n // añadir\n\t}\n\treturn σ\n}\n\nfunc main() {\n\tprintln(\"héllo wörld 😀\", 总和([]int{1, 2, 3}))\n}"},
+	{"sample.py", "# Привет мир\ndef приветствие(имя):\n    сообщение = f\"Привет, {имя}! 👋\"\n    print(сообщение)\n    return сообщение\n\nfor i in range(3):\n    приветствие(\"Ünïcödé\")\n"},
+	{"digits.go", "package main\n\n// non-ASCII digits are operators, not numbers\nfunc main() {\n\tn := ٣ + ४२\n\tprintln(n, \"٣٤\")\n}\n"},
+	{"digits.py", "x = ٣\ny = x + ४२ * 1.5e-3\nprint(\"٣\", y)"},
+	{"sample.txt", "  función  cálculo(x)  \n\tretorna x × 2 — ok\n  日本語のテキスト\n   末尾の行ü"},
+}
+
+// TestMultilingual runs every sample through parse, DAL, copied segments and FindOriginalIndex, against
+// itself and against an edited copy, and checks that every index is in the text at the start of a rune,
+// and that a text compared with itself matches entirely. It returns the first problem found.
+func TestMultilingual() error {
+	for _, sample := range MULTILINGUAL_SAMPLES {
+		edited := strings.Replace(sample.text, "\n", "\n// ñ 変更\n", 2)
+		for _, otherText := range []string{sample.text, edited} {
+			parsedTextObject1 := code_parser.ParseCode(sample.text, sample.path)
+			parsedTextObject2 := code_parser.ParseCode(otherText, sample.path)
+
+			similarityResult := similarity_compute.ComputeLevenSimilarity(parsedTextObject1, parsedTextObject2)
+			if err := checkIndexes(sample.path, sample.text, similarityResult.Text1SubstringIndexes); err != nil {
+				return err
+			}
+			if err := checkIndexes(sample.path, otherText, similarityResult.Text2SubstringIndexes); err != nil {
+				return err
+			}
+
+			copiedSegmentsResults := similarity_compute.ComputeCopiedSegments(parsedTextObject1, parsedTextObject2)
+			for _, segment := range copiedSegmentsResults.Segments {
+				if err := checkIndexes(sample.path, sample.text, segment.Text1SubstringIndexes); err != nil {
+					return err
+				}
+				if err := checkIndexes(sample.path, otherText, segment.Text2SubstringIndexes); err != nil {
+					return err
+				}
+			}
+
+			if otherText != sample.text {
+				continue
+			}
+			if similarityResult.Percentage != 1 {
+				return fmt.Errorf("%s: similarity with itself is %f", sample.path, similarityResult.Percentage)
+			}
+			matched := safeSlice(sample.text, similarityResult.Text1SubstringIndexes)
+			if strings.TrimSpace(matched) == "" || !strings.Contains(sample.text, strings.TrimSpace(matched)) {
+				return fmt.Errorf("%s: matched %q of itself", sample.path, matched)
+			}
+		}
+
+		// every parsed index maps into the original text at the start of a rune
+		parsedTextObject := code_parser.ParseCode(sample.text, sample.path)
+		for parsedIndex := 0; parsedIndex <= len(parsedTextObject.ParsedCodeText); parsedIndex++ {
+			if parsedIndex < len(parsedTextObject.ParsedCodeText) && !utf8.RuneStart(parsedTextObject.ParsedCodeText[parsedIndex]) {
+				continue
+			}
+			originalIndex := parsedTextObject.FindOriginalIndex(parsedIndex)
+			if err := checkIndex(sample.path, sample.text, originalIndex); err != nil {
+				return fmt.Errorf("parsed index %d: %w", parsedIndex, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkIndexes(path string, text string, indexes similarity_compute.SubstringIndexesObject) error {
+	if err := checkIndex(path, text, indexes.StartIndex); err != nil {
+		return err
+	}
+	if err := checkIndex(path, text, indexes.EndIndex); err != nil {
+		return err
+	}
+	if indexes.StartIndex > indexes.EndIndex {
+		return fmt.Errorf("%s: start %d after end %d", path, indexes.StartIndex, indexes.EndIndex)
+	}
+	return nil
+}
+
+func checkIndex(path string, text string, index int) error {
+	if index < 0 || index > len(text) {
+		return fmt.Errorf("%s: index %d outside of the text of length %d", path, index, len(text))
+	}
+	if index < len(text) && !utf8.RuneStart(text[index]) {
+		return fmt.Errorf("%s: index %d is inside the rune at %q", path, index, text[index-1:index+1])
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package test_util
+
+import "testing"
+
+func TestMultilingualSamples(t *testing.T) {
+	if err := TestMultilingual(); err != nil {
+		t.Fatal(err)
+	}
+}
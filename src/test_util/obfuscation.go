@@ -0,0 +1,85 @@
+package test_util
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"hercules/src/lexer"
+	"hercules/src/obfuscation"
+	"hercules/src/syntax_tree"
+	"math/rand"
+	"strings"
+)
+
+type obfuscationSample struct {
+	path string
+	text string
+}
+
+// source with comments, a counting loop and several functions, so every obfuscation applies
+var OBFUSCATION_SAMPLES = []obfuscationSample{
+	{"sample.go", "package main\n\nimport \"fmt\"\n\n// sum adds the numbers\nfunc sum(numbers []int) int {\n\ttotal := 0\n\tfor i := 0; i < len(numbers); i++ {\n\t\ttotal += numbers[i] // add\n\t}\n\treturn total\n}\n\n// scale multiplies every number\nfunc scale(numbers []int, factor int) []int {\n\tscaled := make([]int, len(numbers))\n\tfor i, number := range numbers {\n\t\tscaled[i] = number * factor\n\t}\n\treturn scaled\n}\n\nfunc main() {\n\tfmt.Println(sum(scale([]int{1, 2, 3}, 2)))\n}\n"},
+	{"sample.py", "import math\n\n# sum adds the numbers\ndef add_all(numbers):\n    total = 0\n    for i in range(len(numbers)):\n        total += numbers[i]  # add\n    return total\n\n\ndef scale(numbers, factor):\n    \"\"\"multiplies every number\n    by the factor\"\"\"\n    return [number * factor for number in numbers]\n\n\ndef main():\n    print(add_all(scale([1, 2, 3], math.pi)))\n"},
+	{"Sample.java", "import java.util.List;\n\npublic class Sample {\n    // sum adds the numbers\n    static int sum(int[] numbers) {\n        int total = 0;\n        for (int i = 0; i < numbers.length; i++) {\n            total += numbers[i]; // add\n        }\n        return total;\n    }\n\n    /* scale multiplies every number */\n    static int[] scale(int[] numbers, int factor) {\n        int[] scaled = new int[numbers.length];\n        for (int i = 0; i < numbers.length; i++) {\n            scaled[i] = numbers[i] * factor;\n        }\n        return scaled;\n    }\n}\n"},
+	{"sample.js", "// sum adds the numbers\nfunction sum(numbers) {\n  let total = 0;\n  for (let i = 0; i < numbers.length; i++) {\n    total += numbers[i]; // add\n  }\n  return total;\n}\n\nfunction scale(numbers, factor) {\n  return numbers.map((number) => number * factor);\n}\n\nconsole.log(sum(scale([1, 2, 3], 2)));\n"},
+	{"sample.c", "#include <stdio.h>\n\n/* sum adds the numbers */\nint sum(int *numbers, int count) {\n    int total = 0;\n    for (int i = 0; i < count; i++) {\n        total += numbers[i]; // add\n    }\n    return total;\n}\n\nint main(void) {\n    int numbers[] = {1, 2, 3};\n    printf(\"%d\\n\", sum(numbers, 3));\n    return 0;\n}\n"},
+}
+
+// TestObfuscations applies every obfuscation to every sample with a few seeds, and checks that it
+// changed the sample, that the obfuscated files still parse, and that renaming kept the normalized
+// tokens. It returns the first problem found.
+func TestObfuscations(seeds int) error {
+	for _, sample := range OBFUSCATION_SAMPLES {
+		language := lexer.LanguageFromPath(sample.path)
+		for seed := 0; seed < seeds; seed++ {
+			for _, name := range obfuscation.ALL_OBFUSCATIONS {
+				files, applied := obfuscation.Obfuscate(sample.text, sample.path, []string{name}, rand.New(rand.NewSource(int64(seed))))
+				if len(applied) != 1 || strings.Join(files, "") == sample.text {
+					return fmt.Errorf("%s: %s with seed %d didn't change it", sample.path, name, seed)
+				}
+				for _, file := range files {
+					if err := checkParses(sample.path, file); err != nil {
+						return fmt.Errorf("%s: %s with seed %d: %w\n%s", sample.path, name, seed, err, file)
+					}
+				}
+				if name == obfuscation.OBFUSCATION_RENAME && normalizedTokens(language, files[0]) != normalizedTokens(language, sample.text) {
+					return fmt.Errorf("%s: renaming with seed %d changed the normalized tokens\n%s", sample.path, seed, files[0])
+				}
+			}
+
+			files, applied := obfuscation.Obfuscate(sample.text, sample.path, obfuscation.ALL_OBFUSCATIONS, rand.New(rand.NewSource(int64(seed))))
+			for _, file := range files {
+				if err := checkParses(sample.path, file); err != nil {
+					return fmt.Errorf("%s: %s with seed %d: %w\n%s", sample.path, strings.Join(applied, "+"), seed, err, file)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func checkParses(path string, text string) error {
+	if strings.HasSuffix(path, ".go") {
+		_, err := parser.ParseFile(token.NewFileSet(), path, text, 0)
+		return err
+	}
+	root, err := syntax_tree.Parse(text, path)
+	if err != nil {
+		return err
+	}
+	var parseError error
+	root.Walk(func(node *syntax_tree.Node) {
+		if node.Kind == "ERROR" && parseError == nil {
+			parseError = fmt.Errorf("syntax error at lines %d-%d", node.StartLine, node.EndLine)
+		}
+	})
+	return parseError
+}
+
+func normalizedTokens(language *lexer.Language, text string) string {
+	var normalized []string
+	for _, token := range lexer.Lex(language, text) {
+		normalized = append(normalized, token.Normalized())
+	}
+	return strings.Join(normalized, " ")
+}
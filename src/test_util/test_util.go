@@ -5,6 +5,7 @@ import (
 	"hercules/src/code_parser"
 	"hercules/src/similarity_compute"
 	"hercules/src/util"
+	"unicode/utf8"
 )
 
 func TestComparison(path1 string, path2 string) {
@@ -12,8 +13,8 @@ func TestComparison(path1 string, path2 string) {
 	util.Check(err)
 	text1 := data[path1]
 	text2 := data[path2]
-	parsedTextObject1 := code_parser.ParseCodeText(text1)
-	parsedTextObject2 := code_parser.ParseCodeText(text2)
+	parsedTextObject1 := code_parser.ParseCode(text1, path1)
+	parsedTextObject2 := code_parser.ParseCode(text2, path2)
 
 	similarityResult := similarity_compute.ComputeLevenSimilarity(
 		parsedTextObject1,
@@ -21,9 +22,23 @@ func TestComparison(path1 string, path2 string) {
 	)
 
 	fmt.Println(similarityResult.Percentage)
-	outText1 := data[path1][similarityResult.Text1SubstringIndexes.StartIndex:similarityResult.Text1SubstringIndexes.EndIndex]
-	outText2 := data[path2][similarityResult.Text2SubstringIndexes.StartIndex:similarityResult.Text2SubstringIndexes.EndIndex]
+	outText1 := safeSlice(text1, similarityResult.Text1SubstringIndexes)
+	outText2 := safeSlice(text2, similarityResult.Text2SubstringIndexes)
 	fmt.Println(outText1)
 	fmt.Println("----------------")
 	fmt.Println(outText2)
 }
+
+// safeSlice slices text at the indexes, clamped to the text and moved back to the start of a rune
+func safeSlice(text string, indexes similarity_compute.SubstringIndexesObject) string {
+	startIndex := runeStart(text, util.Max(0, util.Min(indexes.StartIndex, len(text))))
+	endIndex := runeStart(text, util.Max(startIndex, util.Min(indexes.EndIndex, len(text))))
+	return text[startIndex:endIndex]
+}
+
+func runeStart(text string, index int) int {
+	for index > 0 && index < len(text) && !utf8.RuneStart(text[index]) {
+		index--
+	}
+	return index
+}
@@ -0,0 +1,76 @@
+package tfidf
+
+import (
+	"strings"
+	"unicode"
+)
+
+const MIN_KEYWORD_LENGTH = 3 // shorter words like i or x aren't worth searching for
+
+// TokenizeCode splits code into lowercased identifiers, and compound identifiers also into their
+// camelCase and snake_case parts, e.g. parseHTTPRequest gives parsehttprequest, parse, http and request.
+// Numbers, operators and words shorter than MIN_KEYWORD_LENGTH are dropped.
+func TokenizeCode(input string) []string {
+	var tokens []string
+	for _, word := range strings.FieldsFunc(input, func(char rune) bool {
+		return !(unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_')
+	}) {
+		parts := SplitIdentifier(word)
+		if len(parts) > 1 {
+			tokens = appendKeyword(tokens, strings.ToLower(strings.Trim(word, "_")))
+		}
+		for _, part := range parts {
+			tokens = appendKeyword(tokens, part)
+		}
+	}
+	return tokens
+}
+
+// SplitIdentifier splits an identifier into its lowercased words, at underscores, digits and case changes,
+// keeping acronyms together, e.g. snake_case, camelCase and parseHTTPRequest2 give snake case, camel case
+// and parse http request
+func SplitIdentifier(identifier string) []string {
+	var parts []string
+	runes := []rune(identifier)
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			parts = append(parts, strings.ToLower(string(runes[start:end])))
+		}
+		start = -1
+	}
+	for i, char := range runes {
+		if !unicode.IsLetter(char) {
+			flush(i)
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		previous := runes[i-1]
+		// a new word starts at fooBar and at the R of HTTPRequest
+		isCamelBoundary := unicode.IsUpper(char) && unicode.IsLower(previous)
+		isAcronymEnd := unicode.IsUpper(char) && unicode.IsUpper(previous) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if isCamelBoundary || isAcronymEnd {
+			flush(i)
+			start = i
+		}
+	}
+	flush(len(runes))
+	return parts
+}
+
+func appendKeyword(tokens []string, word string) []string {
+	if len([]rune(word)) < MIN_KEYWORD_LENGTH {
+		return tokens
+	}
+	return append(tokens, word)
+}
+
+// NewForKeywords new model for picking search keywords, with TokenizeCode and the common stopwords
+func NewForKeywords() *TFIDF {
+	f := NewTokenizerFunc(TokenizeCode)
+	f.InitStopWords()
+	return f
+}
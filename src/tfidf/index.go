@@ -4,7 +4,6 @@ package tfidf
 import (
 	"crypto/md5"
 	"encoding/hex"
-	"log"
 	"math"
 
 	"github.com/wilcosheh/tfidf/seg"
@@ -13,7 +12,7 @@ import (
 
 // TFIDF tfidf model
 type TFIDF struct {
-	docIndex  map[string]int         // train document index in TermFreqs
+	docIndex  map[string]int         // train document index in TermFreqs, -1 if its term frequencies aren't kept
 	termFreqs []map[string]int       // term frequency for each train document
 	termDocs  map[string]int         // documents number for each term in train data
 	n         int                    // number of documents in train data
@@ -43,18 +42,11 @@ func NewTokenizer(tokenizer seg.Tokenizer) *TFIDF {
 	}
 }
 
-func (f *TFIDF) InitStopWords() {
-	if f.stopWords == nil {
-		f.stopWords = make(map[string]interface{})
-	}
-
-	lines, err := util.ReadLines("../data/stopword")
-	if err != nil {
-		log.Printf("init stop words with error: %s", err)
-	}
-
-	for _, w := range lines {
-		f.stopWords[w] = nil
+// InitStopWords filters the embedded common stopwords and those of the languages, e.g. "go" or "python"
+func (f *TFIDF) InitStopWords(languages ...string) {
+	f.AddStopWords(StopWords(COMMON_STOP_WORDS)...)
+	for _, language := range languages {
+		f.AddStopWords(StopWords(language)...)
 	}
 }
 
@@ -90,16 +82,16 @@ func (f *TFIDF) AddDocs(docs []string, tokenizerArgs ...tokenizerDef) {
 	}
 	for _, doc := range docs {
 		h := hash(doc)
-		if f.docHashPos(h) >= 0 {
-			return
+		if _, ok := f.docIndex[h]; ok {
+			continue
 		}
 
 		termFreq := f.termFreq(doc, tokenizer)
 		if len(termFreq) == 0 {
-			return
+			continue // e.g. a file of only base code, the other documents are still added
 		}
 
-		f.docIndex[h] = f.n
+		f.docIndex[h] = len(f.termFreqs)
 		f.n++
 
 		f.termFreqs = append(f.termFreqs, termFreq)
@@ -110,6 +102,33 @@ func (f *TFIDF) AddDocs(docs []string, tokenizerArgs ...tokenizerDef) {
 	}
 }
 
+// AddBackgroundDocs adds documents to the document frequencies only, without keeping their term
+// frequencies, so a large corpus fits in memory. Cal of such a document tokenizes it again.
+func (f *TFIDF) AddBackgroundDocs(docs []string, tokenizerArgs ...tokenizerDef) {
+	tokenizer := f.tokenizer.Seg
+	if len(tokenizerArgs) > 0 {
+		tokenizer = tokenizerArgs[0]
+	}
+	for _, doc := range docs {
+		h := hash(doc)
+		if _, ok := f.docIndex[h]; ok {
+			continue
+		}
+
+		termFreq := f.termFreq(doc, tokenizer)
+		if len(termFreq) == 0 {
+			continue
+		}
+
+		f.docIndex[h] = -1
+		f.n++
+
+		for term := range termFreq {
+			f.termDocs[term]++
+		}
+	}
+}
+
 // Cal calculate tf-idf weight for specified document
 func (f *TFIDF) Cal(doc string, tokenizerArgs ...tokenizerDef) (weight map[string]float64) {
 	tokenizer := f.tokenizer.Seg
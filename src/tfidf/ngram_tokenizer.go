@@ -0,0 +1,100 @@
+package tfidf
+
+import (
+	"fmt"
+	"hercules/src/lexer"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	TOKENIZER_CHAR  = "char"  // non-letter characters, or n-grams of them without whitespace with char:<n>
+	TOKENIZER_TOKEN = "token" // n-grams of normalized tokens, e.g. `ID ( ID )` with token:4
+)
+
+const DEFAULT_TOKENIZER = TOKENIZER_CHAR
+const DEFAULT_TOKEN_NGRAM_LENGTH = 3
+
+var TOKENIZERS = []string{TOKENIZER_CHAR, TOKENIZER_TOKEN}
+
+// funcTokenizer makes a tokenize function a seg.Tokenizer, so a TFIDF uses it for both AddDocs and Cal
+type funcTokenizer func(string) []string
+
+func (tokenize funcTokenizer) Seg(text string) []string {
+	return tokenize(text)
+}
+
+func (tokenize funcTokenizer) Free() {}
+
+// NewTokenizerFunc new model with the tokenize function, e.g. one from ParseTokenizer
+func NewTokenizerFunc(tokenize func(string) []string) *TFIDF {
+	return NewTokenizer(funcTokenizer(tokenize))
+}
+
+// ParseTokenizer parses a tokenizer name with an optional n-gram length,
+// e.g. "char" (TokenizeCharLevelNoAlpha), "char:3" or "token:4"
+func ParseTokenizer(name string) (func(string) []string, error) {
+	kind, lengthText, hasLength := strings.Cut(strings.ToLower(strings.TrimSpace(name)), ":")
+	n := 1
+	if kind == TOKENIZER_TOKEN {
+		n = DEFAULT_TOKEN_NGRAM_LENGTH
+	}
+	if hasLength {
+		var err error
+		n, err = strconv.Atoi(lengthText)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid n-gram length %q in tokenizer %q", lengthText, name)
+		}
+	}
+
+	switch kind {
+	case TOKENIZER_CHAR:
+		if n == 1 {
+			return TokenizeCharLevelNoAlpha, nil
+		}
+		return TokenizeCharNGrams(n), nil
+	case TOKENIZER_TOKEN:
+		return TokenizeTokenNGrams(n), nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q, must be one of %s, with an optional :<n>", name, strings.Join(TOKENIZERS, ", "))
+	}
+}
+
+// TokenizeCharNGrams returns n-grams of the non-letter characters without whitespace,
+// e.g. `){` and `();` shapes for n=3, which are far less common than single characters
+func TokenizeCharNGrams(n int) func(string) []string {
+	return func(input string) []string {
+		var chars []rune
+		for _, char := range input {
+			if !unicode.IsLetter(char) && !unicode.IsSpace(char) {
+				chars = append(chars, char)
+			}
+		}
+		tokens := make([]string, 0, len(chars))
+		for i := 0; i+n <= len(chars); i++ {
+			tokens = append(tokens, string(chars[i:i+n]))
+		}
+		return tokens
+	}
+}
+
+// TokenizeTokenNGrams returns n-grams of the normalized token stream, e.g. `ID ( ID )` for n=4.
+// Any language is lexed with lexer.GENERIC, so files and their parsed text give the same n-grams.
+func TokenizeTokenNGrams(n int) func(string) []string {
+	return func(input string) []string {
+		var normalizedTokens []string
+		for _, token := range lexer.Lex(lexer.GENERIC, input) {
+			normalizedToken := token.Normalized()
+			if token.Text == lexer.NORMALIZED_LITERAL {
+				normalizedToken = lexer.NORMALIZED_LITERAL // already normalized
+			}
+			normalizedTokens = append(normalizedTokens, normalizedToken)
+		}
+		tokens := make([]string, 0, len(normalizedTokens))
+		for i := 0; i+n <= len(normalizedTokens); i++ {
+			tokens = append(tokens, strings.Join(normalizedTokens[i:i+n], " "))
+		}
+		return tokens
+	}
+}
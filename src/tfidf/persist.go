@@ -0,0 +1,119 @@
+package tfidf
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"os"
+)
+
+const MODEL_VERSION = 2 // 2: terms from TokenizeCode
+
+// savedModel is what a model file holds: the document frequencies and the hashes of the documents,
+// so documents already counted aren't counted again when the model is added to or merged
+type savedModel struct {
+	Version   int
+	N         int
+	TermDocs  map[string]int
+	DocHashes []string
+}
+
+// Save writes the document frequencies of the model to path, gzipped gob.
+// The term frequencies of the documents aren't saved, so a loaded model only gives the IDF.
+func (f *TFIDF) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	docHashes := make([]string, 0, len(f.docIndex))
+	for h := range f.docIndex {
+		docHashes = append(docHashes, h)
+	}
+	model := savedModel{
+		Version:   MODEL_VERSION,
+		N:         f.n,
+		TermDocs:  f.termDocs,
+		DocHashes: docHashes,
+	}
+
+	gzipWriter := gzip.NewWriter(file)
+	if err := gob.NewEncoder(gzipWriter).Encode(model); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Load reads a model written by Save, with the default tokenizer. Merge it into a model with the
+// tokenizer it was built with, e.g. NewForKeywords, to use it
+func Load(path string) (*TFIDF, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a TFIDF model: %w", path, err)
+	}
+	var model savedModel
+	if err := gob.NewDecoder(gzipReader).Decode(&model); err != nil {
+		return nil, fmt.Errorf("%s is not a TFIDF model: %w", path, err)
+	}
+	if model.Version != MODEL_VERSION {
+		return nil, fmt.Errorf("%s is a version %d TFIDF model, expected version %d", path, model.Version, MODEL_VERSION)
+	}
+
+	f := New()
+	f.n = model.N
+	if model.TermDocs != nil {
+		f.termDocs = model.TermDocs
+	}
+	for _, h := range model.DocHashes {
+		f.docIndex[h] = -1
+	}
+	return f, nil
+}
+
+// Merge adds the documents of other to the model. Documents in both are counted once if other
+// kept their term frequencies, and twice otherwise, as their terms can't be told apart.
+func (f *TFIDF) Merge(other *TFIDF) {
+	for term, docs := range other.termDocs {
+		f.termDocs[term] += docs
+	}
+	f.n += other.n
+
+	for h, pos := range other.docIndex {
+		if _, ok := f.docIndex[h]; ok {
+			if pos >= 0 {
+				for term := range other.termFreqs[pos] {
+					f.termDocs[term]--
+					if f.termDocs[term] == 0 {
+						delete(f.termDocs, term)
+					}
+				}
+				f.n--
+			}
+			continue
+		}
+		if pos >= 0 {
+			f.docIndex[h] = len(f.termFreqs)
+			f.termFreqs = append(f.termFreqs, other.termFreqs[pos])
+		} else {
+			f.docIndex[h] = -1
+		}
+	}
+}
+
+func (f *TFIDF) NumberOfDocs() int {
+	return f.n
+}
+
+func (f *TFIDF) NumberOfTerms() int {
+	return len(f.termDocs)
+}
@@ -0,0 +1,40 @@
+package tfidf
+
+import (
+	"embed"
+	"strings"
+)
+
+const COMMON_STOP_WORDS = "common" // stopwords/common.txt, for any language
+
+// stopwords/<language>.txt, one word a line, named like lexer.Language.Name
+//
+//go:embed stopwords/*.txt
+var stopWordFiles embed.FS
+
+// StopWords returns the embedded stopwords of a language, its keywords and common standard library
+// names, lowercased. An unknown language has none.
+func StopWords(language string) []string {
+	data, err := stopWordFiles.ReadFile("stopwords/" + language + ".txt")
+	if err != nil {
+		return nil
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		word := strings.ToLower(strings.TrimSpace(line))
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// RemoveStopWords removes the stopwords of the languages from the weights, in place
+func RemoveStopWords(weights map[string]float64, languages ...string) map[string]float64 {
+	for _, language := range languages {
+		for _, word := range StopWords(language) {
+			delete(weights, word)
+		}
+	}
+	return weights
+}
@@ -5,11 +5,20 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"golang.org/x/exp/constraints"
 )
 
+const MIN_SIGNIFICANT_LINE_LENGTH = 4 // characters, shorter lines like "}" or "end" are in every file
+
+// IsSignificantLine is true if line without its surrounding whitespace is at least MIN_SIGNIFICANT_LINE_LENGTH characters
+func IsSignificantLine(line string) bool {
+	return utf8.RuneCountInString(strings.TrimSpace(line)) >= MIN_SIGNIFICANT_LINE_LENGTH
+}
+
 type Pair[T constraints.Ordered] struct {
 	Key   string
 	Value T
@@ -29,12 +38,16 @@ func Cleanup(dir string) {
 	fmt.Printf("Cleaned up temp directory %s\n", dir)
 }
 
+// Reverse reverses the runes of s, keeping the bytes of every rune (and of invalid UTF-8) as they are,
+// so the reverse has the same length in bytes
 func Reverse(s string) string {
-	runes := []rune(s)
-	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
-		runes[i], runes[j] = runes[j], runes[i]
+	reversed := make([]byte, len(s))
+	for i := 0; i < len(s); {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		copy(reversed[len(s)-i-size:], s[i:i+size])
+		i += size
 	}
-	return string(runes)
+	return string(reversed)
 }
 
 func Check(e error) {
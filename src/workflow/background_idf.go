@@ -0,0 +1,45 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/tfidf"
+	"hercules/src/util"
+)
+
+const BACKGROUND_IDF_BATCH_SIZE = 500 // files read at once, so a large corpus isn't read into memory
+
+// BuildBackgroundIDF counts the document frequencies of every code file under dirs, on top of the
+// models to merge, and saves the model to outPath. With it, the keywords of a submission's files are
+// weighted against code in general instead of the submission's other files.
+func BuildBackgroundIDF(dirs []string, mergePaths []string, outPath string) error {
+	model := tfidf.NewForKeywords()
+	for _, mergePath := range mergePaths {
+		mergeModel, err := tfidf.Load(mergePath)
+		if err != nil {
+			return err
+		}
+		model.Merge(mergeModel)
+		fmt.Printf("Merged %s\n", mergePath)
+	}
+
+	for _, dir := range dirs {
+		filePaths, err := util.GetFilePaths(dir)
+		if err != nil {
+			return err
+		}
+		filePaths = util.RemoveNonCodeFiles(filePaths)
+		fmt.Printf("Counting %d files in %s\n", len(filePaths), dir)
+
+		for batchStart := 0; batchStart < len(filePaths); batchStart += BACKGROUND_IDF_BATCH_SIZE {
+			batch := filePaths[batchStart:util.Min(batchStart+BACKGROUND_IDF_BATCH_SIZE, len(filePaths))]
+			allDataMap, err := util.MultipleFileRead(batch, TEXT_MAX_LENGTH)
+			if err != nil {
+				return err
+			}
+			model.AddBackgroundDocs(loadAllData(allDataMap))
+		}
+	}
+
+	fmt.Printf("Saving a model of %d documents and %d terms to %s\n", model.NumberOfDocs(), model.NumberOfTerms(), outPath)
+	return model.Save(outPath)
+}
@@ -0,0 +1,143 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/git_repo"
+	"log"
+	"sort"
+	"strings"
+)
+
+const (
+	EXCLUDED_SAME_FORK_NETWORK = "same fork network as submission"
+	EXCLUDED_SUBMITTER_OWNER   = "owned by submitter"
+	EXCLUDED_ALLOWLISTED_OWNER = "owned by allowlisted owner"
+	EXCLUDED_MIRROR            = "mirror of same upstream"
+)
+
+type ExcludedCandidate struct {
+	RepoName   string
+	Reason     string
+	MergedInto string // only set for mirrors, the repo the results were merged into
+}
+
+// filterCandidateRepos drops candidates in the submission's fork network or owned by
+// the submitter / an allowlisted owner, and collapses mirrors of one upstream into one
+// candidate. Repos whose metadata can't be fetched are kept as they are.
+func filterCandidateRepos(
+	submissionRepoName string,
+	possibleRepoMap map[string][]*MiniParseCodeWorkflowScanResult,
+	options Options,
+) (map[string][]*MiniParseCodeWorkflowScanResult, []ExcludedCandidate) {
+	var excluded []ExcludedCandidate
+
+	excludedOwners := make(map[string]bool)
+	for _, owner := range options.ExcludedOwners {
+		excludedOwners[strings.ToLower(owner)] = true
+	}
+	submitter := strings.ToLower(options.Submitter)
+	if submitter == "" {
+		submitter = strings.ToLower(git_repo.GetOwnerFromRepoName(submissionRepoName))
+	}
+
+	submissionNetworkRoot := ""
+	if submissionRepoName != "" && strings.Count(submissionRepoName, "/") == 1 {
+		submissionRepository, err := git_repo.GetRepository(submissionRepoName)
+		if err != nil {
+			log.Printf("Could not fetch submission repository metadata: %v", err)
+		} else {
+			submissionNetworkRoot = strings.ToLower(submissionRepository.NetworkRoot())
+		}
+	}
+
+	// deterministic order, since the same upstream can be reached from several candidates
+	candidateNames := make([]string, 0, len(possibleRepoMap))
+	for candidateName := range possibleRepoMap {
+		candidateNames = append(candidateNames, candidateName)
+	}
+	sort.Strings(candidateNames)
+
+	upstreamGroups := make(map[string][]string) // map[upstreamKey]candidateNames
+	var upstreamKeys []string
+	for _, candidateName := range candidateNames {
+		// same as getTopNRepos, single hits are never shown so don't spend API calls on them
+		if len(possibleRepoMap[candidateName]) <= 1 {
+			continue
+		}
+		// repos of the corpus index have no owner or fork network
+		if isLocalRepo(candidateName) {
+			upstreamKeys = append(upstreamKeys, candidateName)
+			upstreamGroups[candidateName] = []string{candidateName}
+			continue
+		}
+
+		owner := strings.ToLower(git_repo.GetOwnerFromRepoName(candidateName))
+		if submitter != "" && owner == submitter {
+			excluded = append(excluded, ExcludedCandidate{RepoName: candidateName, Reason: EXCLUDED_SUBMITTER_OWNER})
+			continue
+		}
+		if excludedOwners[owner] {
+			excluded = append(excluded, ExcludedCandidate{RepoName: candidateName, Reason: EXCLUDED_ALLOWLISTED_OWNER})
+			continue
+		}
+
+		upstreamKey := strings.ToLower(candidateName)
+		candidateRepository, err := git_repo.GetRepository(candidateName)
+		if err != nil {
+			log.Printf("Could not fetch metadata of %s, keeping it: %v", candidateName, err)
+		} else {
+			if submissionNetworkRoot != "" && strings.ToLower(candidateRepository.NetworkRoot()) == submissionNetworkRoot {
+				excluded = append(excluded, ExcludedCandidate{RepoName: candidateName, Reason: EXCLUDED_SAME_FORK_NETWORK})
+				continue
+			}
+			upstreamKey = candidateRepository.UpstreamKey()
+		}
+
+		if _, ok := upstreamGroups[upstreamKey]; !ok {
+			upstreamKeys = append(upstreamKeys, upstreamKey)
+		}
+		upstreamGroups[upstreamKey] = append(upstreamGroups[upstreamKey], candidateName)
+	}
+
+	filteredRepoMap := make(map[string][]*MiniParseCodeWorkflowScanResult)
+	for _, upstreamKey := range upstreamKeys {
+		group := upstreamGroups[upstreamKey]
+		representative := pickUpstreamRepresentative(upstreamKey, group, possibleRepoMap)
+		for _, candidateName := range group {
+			filteredRepoMap[representative] = append(filteredRepoMap[representative], possibleRepoMap[candidateName]...)
+			if candidateName != representative {
+				excluded = append(excluded, ExcludedCandidate{
+					RepoName:   candidateName,
+					Reason:     EXCLUDED_MIRROR,
+					MergedInto: representative,
+				})
+			}
+		}
+	}
+	return filteredRepoMap, excluded
+}
+
+// pickUpstreamRepresentative prefers the upstream itself, then the candidate with the most hits
+func pickUpstreamRepresentative(
+	upstreamKey string,
+	group []string,
+	possibleRepoMap map[string][]*MiniParseCodeWorkflowScanResult,
+) string {
+	representative := group[0]
+	for _, candidateName := range group {
+		if strings.ToLower(candidateName) == upstreamKey {
+			return candidateName
+		}
+		if len(possibleRepoMap[candidateName]) > len(possibleRepoMap[representative]) {
+			representative = candidateName
+		}
+	}
+	return representative
+}
+
+func describeExcludedCandidate(excludedCandidate ExcludedCandidate) string {
+	if excludedCandidate.MergedInto != "" {
+		return fmt.Sprintf("%s, merged into %s", excludedCandidate.Reason, excludedCandidate.MergedInto)
+	}
+	return excludedCandidate.Reason
+}
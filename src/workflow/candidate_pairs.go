@@ -0,0 +1,82 @@
+package workflow
+
+import (
+	"hercules/src/base_code"
+	"hercules/src/minhash"
+	"hercules/src/util"
+	"sort"
+	"strings"
+)
+
+// filePair is a file of the submission and a file of the challengee repo, by index in paths and challengeePaths
+type filePair struct {
+	index           int
+	challengeeIndex int
+}
+
+// candidatePairs are the pairs of files of the submission and of the challengee repo that are compared,
+// in order of index: those of the same language or of a language family. With an LSH index only those
+// whose MinHash signatures share a band are, so the pairs not found are never looked at. Files of a
+// language family are signed by their language-neutral token streams too.
+func candidatePairs(
+	paths []string,
+	dataMap map[string]string,
+	challengeePaths []string,
+	challengeeDataMap map[string]string,
+	baseCode *base_code.BaseCode,
+	options Options,
+) []filePair {
+	isComparable := func(pair filePair) bool {
+		path, challengeePath := paths[pair.index], challengeePaths[pair.challengeeIndex]
+		return util.IsExtensionSame(path, challengeePath) || isCrossLanguagePair(path, challengeePath, options)
+	}
+
+	var candidates []filePair
+	if options.LSHBands == 0 {
+		for i := range paths {
+			for j := range challengeePaths {
+				if pair := (filePair{i, j}); isComparable(pair) {
+					candidates = append(candidates, pair)
+				}
+			}
+		}
+		return candidates
+	}
+
+	challengeeIndexes := make(map[string]int, len(challengeePaths))
+	for j, challengeePath := range challengeePaths {
+		challengeeIndexes[challengeePath] = j
+	}
+	found := make(map[filePair]bool)
+	for _, crossLanguage := range []bool{false, true} {
+		if crossLanguage && len(options.LanguageFamilies) == 0 {
+			continue
+		}
+		index := minhash.NewIndex(options.LSHBands, options.LSHRows)
+		for _, challengeePath := range challengeePaths {
+			index.Add(challengeePath, fileSignature(challengeeDataMap[challengeePath], challengeePath, crossLanguage, baseCode, index.Hashes()))
+		}
+		for i, path := range paths {
+			for _, challengeePath := range index.Candidates(fileSignature(dataMap[path], path, crossLanguage, baseCode, index.Hashes())) {
+				pair := filePair{i, challengeeIndexes[challengeePath]}
+				if !found[pair] && isComparable(pair) {
+					found[pair] = true
+					candidates = append(candidates, pair)
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].index != candidates[j].index {
+			return candidates[i].index < candidates[j].index
+		}
+		return candidates[i].challengeeIndex < candidates[j].challengeeIndex
+	})
+	return candidates
+}
+
+// fileSignature is the MinHash signature of the normalized tokens of a file without its base code
+func fileSignature(text string, path string, crossLanguage bool, baseCode *base_code.BaseCode, hashes int) minhash.Signature {
+	parsedCodeText := parseCode(baseCode.StripBaseLines(text), path, crossLanguage)
+	return minhash.Sign(strings.Fields(parsedCodeText.ParsedCodeText), hashes)
+}
@@ -0,0 +1,153 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/base_code"
+	"hercules/src/clustering"
+	"hercules/src/corpus_index"
+	"hercules/src/git_history"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// cohortRepo is a submission or an external repo of a cohort, read once for all its comparisons
+type cohortRepo struct {
+	dir        string
+	allDataMap map[string]string
+	history    *git_history.History
+}
+
+// BuildCohortGraph compares every two submissions, and every submission with every external repo, e.g.
+// known solutions, and the repos of the corpus index of the options, repo to repo. Their combined
+// similarities are the edges of the graph, external repos aren't compared with each other.
+func BuildCohortGraph(submissionDirs []string, externalDirs []string, options Options) (*clustering.Graph, error) {
+	baseCode, err := base_code.Load(options.BaseSources)
+	if err != nil {
+		return nil, err
+	}
+	if options.CorpusIndexPath != "" {
+		corpusIndex, err := corpus_index.Load(options.CorpusIndexPath)
+		if err != nil {
+			return nil, err
+		}
+		externalDirs = append(externalDirs, corpusIndex.Repos()...)
+	}
+
+	graph := clustering.Graph{}
+	var submissions, externals []*cohortRepo
+	seen := make(map[string]bool)
+	for i, dir := range append(append([]string{}, submissionDirs...), externalDirs...) {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if seen[absDir] {
+			continue
+		}
+		seen[absDir] = true
+		allDataMap, err := readCodeFiles(absDir)
+		if err != nil {
+			return nil, err
+		}
+		history, err := git_history.Open(absDir)
+		if err != nil {
+			history = nil // the copy direction of its pairs is inconclusive
+		}
+		repo := &cohortRepo{dir: absDir, allDataMap: allDataMap, history: history}
+		isExternal := i >= len(submissionDirs)
+		if isExternal {
+			externals = append(externals, repo)
+		} else {
+			submissions = append(submissions, repo)
+		}
+		graph.Nodes = append(graph.Nodes, clustering.Node{ID: absDir, Label: filepath.Base(absDir), External: isExternal})
+	}
+
+	type repoPair struct{ repo1, repo2 *cohortRepo }
+	var pairs []repoPair
+	for i, submission := range submissions {
+		for _, otherSubmission := range submissions[i+1:] {
+			pairs = append(pairs, repoPair{submission, otherSubmission})
+		}
+		for _, external := range externals {
+			pairs = append(pairs, repoPair{submission, external})
+		}
+	}
+	for i, pair := range pairs {
+		fmt.Printf("Comparing repo pair %d of %d: %s and %s\n", i+1, len(pairs), pair.repo1.dir, pair.repo2.dir)
+		if edge := compareCohortRepos(pair.repo1, pair.repo2, baseCode, options); edge != nil {
+			graph.Edges = append(graph.Edges, *edge)
+		}
+	}
+	return &graph, nil
+}
+
+// compareCohortRepos compares the repo with fewer files with the other, as the weighted scores are of
+// the files of the first. It returns nil if the repos share nothing or are too different in size.
+func compareCohortRepos(repo1 *cohortRepo, repo2 *cohortRepo, baseCode *base_code.BaseCode, options Options) *clustering.Edge {
+	submission, challengee := repo1, repo2
+	if len(repo2.allDataMap) < len(repo1.allDataMap) {
+		submission, challengee = repo2, repo1
+	}
+	result := compareRepos(
+		submission.dir, loadAllData(submission.allDataMap), submission.allDataMap,
+		challengee.dir, challengee.dir, challengee.dir,
+		baseCode, submission.history, challengee.history, options,
+	)
+	if result == nil || result.CombinedSimilarityWeighted == 0 {
+		return nil
+	}
+
+	// the repo whose matched code was there first in more files is the older one
+	olderVotes := 0
+	for _, matchedFile := range result.MatchedFiles {
+		switch matchedFile.CopyDirection {
+		case git_history.SOURCE_PREDATES_SUBMISSION:
+			olderVotes--
+		case git_history.SUBMISSION_PREDATES_SOURCE:
+			olderVotes++
+		}
+	}
+	edge := clustering.Edge{Source: repo1.dir, Target: repo2.dir, Similarity: result.CombinedSimilarityWeighted}
+	if olderVotes > 0 {
+		edge.Older = submission.dir
+	} else if olderVotes < 0 {
+		edge.Older = challengee.dir
+	}
+	return &edge
+}
+
+func RenderClustersTable(graph *clustering.Graph, clusters []clustering.Cluster) {
+	fmt.Println("-----------------------------------")
+	fmt.Printf("%d Clusters of %d Repositories\n", len(clusters), len(graph.Nodes))
+	if len(clusters) == 0 {
+		fmt.Println("No repositories share enough code to be clustered.")
+		return
+	}
+
+	labels, memberLabels := make(map[string]string, len(graph.Nodes)), make(map[string]string, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		labels[node.ID], memberLabels[node.ID] = node.Label, node.Label
+		if node.External {
+			memberLabels[node.ID] += " (external)"
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Cluster", "Members", "Likely Origin", "Mean Similarity"})
+	for _, cluster := range clusters {
+		members := make([]string, len(cluster.Members))
+		for i, id := range cluster.Members {
+			members[i] = memberLabels[id]
+		}
+		table.Append([]string{
+			fmt.Sprintf("%d", cluster.ID),
+			strings.Join(members, ", "),
+			fmt.Sprintf("%s (%s)", labels[cluster.Origin], cluster.OriginReason),
+			fmt.Sprintf("%.4f", cluster.MeanSimilarity),
+		})
+	}
+	table.Render()
+}
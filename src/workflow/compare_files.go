@@ -0,0 +1,142 @@
+package workflow
+
+import (
+	"hercules/src/base_code"
+	"hercules/src/code_parser"
+	"hercules/src/score_fusion"
+	"hercules/src/similarity_compute"
+	"hercules/src/util"
+	"math"
+	"strings"
+)
+
+// fileComparison is every metric of a file compared with a matched file
+type fileComparison struct {
+	levenResults          *similarity_compute.SimilarityResults
+	levenSimilarity       float64 // DAL without the base code
+	baseCodeShare         float64
+	winnowingResults      *similarity_compute.WinnowingResults
+	copiedSegmentsResults *similarity_compute.CopiedSegmentsResults
+	gstResults            *similarity_compute.GreedyStringTilingResults
+	structuralResults     *similarity_compute.StructuralResults // nil if the files can't be parsed
+	similarities          map[string]float64                    // by metric, for combineSimilarities
+	combinedSimilarity    float64
+	probability           float64 // of the fusion model of the options, 0 without one
+	copiedLines           int     // non-blank lines of text1 in the copied code, see countCopiedLines
+	copiedLinesShare      float64 // of text1's non-blank lines
+}
+
+// compareFiles computes the metrics of text1 against text2. The CLNAT similarity is given,
+// as it depends on the TFIDF model of the files around them.
+func compareFiles(
+	path1 string, text1 string, parsedCodeText1 *code_parser.ParsedCodeTextObject,
+	path2 string, text2 string, parsedCodeText2 *code_parser.ParsedCodeTextObject,
+	tfidfSimilarity float64,
+	baseCode *base_code.BaseCode,
+	options Options,
+) *fileComparison {
+	comparison := fileComparison{}
+	comparison.levenResults = similarity_compute.ComputeLevenSimilarity(parsedCodeText1, parsedCodeText2)
+
+	// the skeleton code of the assignment doesn't count towards the similarity
+	comparison.baseCodeShare = baseCode.BaseShare(
+		text1,
+		comparison.levenResults.Text1SubstringIndexes.StartIndex,
+		comparison.levenResults.Text1SubstringIndexes.EndIndex,
+	)
+	comparison.levenSimilarity = comparison.levenResults.Percentage * (1 - comparison.baseCodeShare)
+
+	comparison.winnowingResults = similarity_compute.ComputeWinnowingSimilarity(parsedCodeText1, parsedCodeText2)
+	comparison.copiedSegmentsResults = similarity_compute.ComputeCopiedSegments(parsedCodeText1, parsedCodeText2)
+	comparison.gstResults = similarity_compute.ComputeGreedyStringTilingSimilarity(
+		parsedCodeText1,
+		parsedCodeText2,
+		options.GSTMinimumMatch,
+	)
+
+	comparison.similarities = map[string]float64{
+		METRIC_DAL:       comparison.levenSimilarity,
+		METRIC_CLNAT:     tfidfSimilarity,
+		METRIC_WINNOWING: comparison.winnowingResults.Percentage,
+		METRIC_GST:       comparison.gstResults.Percentage,
+	}
+	comparison.structuralResults = computeStructuralSimilarity(comparison.similarities, text1, path1, text2, path2)
+	comparison.combinedSimilarity = combineSimilarities(comparison.similarities, options.CombinedMetrics)
+	comparison.copiedLines, comparison.copiedLinesShare = comparison.countCopiedLines(text1)
+	if options.fusionModel != nil {
+		comparison.probability = options.fusionModel.Probability(comparison.features(text1, text2))
+	}
+	return &comparison
+}
+
+func (comparison *fileComparison) matchedSubtrees() []similarity_compute.MatchedSubtree {
+	if comparison.structuralResults == nil {
+		return nil
+	}
+	return comparison.structuralResults.MatchedSubtrees
+}
+
+// copiedLength is the length of the file's copied code, the sum of its copied segments,
+// or the DAL substring if no segment is long enough
+func (comparison *fileComparison) copiedLength() int {
+	if len(comparison.copiedSegmentsResults.Segments) > 0 {
+		return comparison.copiedSegmentsResults.Text1Length
+	}
+	return comparison.levenResults.Text1SubstringIndexes.EndIndex - comparison.levenResults.Text1SubstringIndexes.StartIndex
+}
+
+// countCopiedLines counts the non-blank lines of text1 in its copied segments, or in the DAL substring if
+// no segment is long enough, like copiedLength, and their share of text1's non-blank lines
+func (comparison *fileComparison) countCopiedLines(text1 string) (int, float64) {
+	lines := strings.Split(text1, "\n")
+	copied := make([]bool, len(lines))
+	markCopied := func(startLine int, endLine int) {
+		for line := util.Max(startLine, 1); line <= util.Min(endLine, len(lines)); line++ {
+			copied[line-1] = true
+		}
+	}
+	if len(comparison.copiedSegmentsResults.Segments) > 0 {
+		for _, segment := range comparison.copiedSegmentsResults.Segments {
+			markCopied(segment.Text1StartLine, segment.Text1EndLine)
+		}
+	} else {
+		markCopied(comparison.levenResults.Text1StartLine, comparison.levenResults.Text1EndLine)
+	}
+
+	copiedLines, nonBlankLines := 0, 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		nonBlankLines++
+		if copied[i] {
+			copiedLines++
+		}
+	}
+	if nonBlankLines == 0 {
+		return 0, 0
+	}
+	return copiedLines, float64(copiedLines) / float64(nonBlankLines)
+}
+
+// features are the inputs of the fusion model for the comparison of text1 with text2
+func (comparison *fileComparison) features(text1 string, text2 string) map[string]float64 {
+	features := map[string]float64{
+		score_fusion.FEATURE_DAL:                    comparison.similarities[METRIC_DAL],
+		score_fusion.FEATURE_CLNAT:                  comparison.similarities[METRIC_CLNAT],
+		score_fusion.FEATURE_WINNOWING:              comparison.winnowingResults.Percentage,
+		score_fusion.FEATURE_WINNOWING_MIN_COVERAGE: math.Min(comparison.winnowingResults.Text1Coverage, comparison.winnowingResults.Text2Coverage),
+		score_fusion.FEATURE_GST:                    comparison.gstResults.Percentage,
+		score_fusion.FEATURE_SEGMENT_COVERAGE:       math.Max(comparison.copiedSegmentsResults.Text1Coverage, comparison.copiedSegmentsResults.Text2Coverage),
+		score_fusion.FEATURE_BASE_CODE_SHARE:        comparison.baseCodeShare,
+		score_fusion.FEATURE_LOG_LINES:              math.Log1p(float64(util.Min(strings.Count(text1, "\n"), strings.Count(text2, "\n")) + 1)),
+	}
+	if astSimilarity, ok := comparison.similarities[METRIC_AST]; ok {
+		features[score_fusion.FEATURE_AST] = astSimilarity
+		features[score_fusion.FEATURE_HAS_AST] = 1
+	}
+	if longer := util.Max(len(text1), len(text2)); longer > 0 {
+		features[score_fusion.FEATURE_SIZE_RATIO] = float64(util.Min(len(text1), len(text2))) / float64(longer)
+	}
+	return features
+}
@@ -0,0 +1,45 @@
+package workflow
+
+import (
+	"hercules/src/git_history"
+	"hercules/src/similarity_compute"
+	"log"
+)
+
+// findCopyDirection labels who had the matched region first, using the history of both repos.
+// Without history on either side the direction is inconclusive.
+func findCopyDirection(
+	submissionHistory *git_history.History,
+	challengeeHistory *git_history.History,
+	path string,
+	data string,
+	challengeePath string,
+	challengeeData string,
+	levenSimilarityResults *similarity_compute.SimilarityResults,
+) string {
+	if submissionHistory == nil || challengeeHistory == nil {
+		return git_history.INCONCLUSIVE
+	}
+
+	submissionProvenance, err := submissionHistory.FindRegionProvenance(
+		path, data,
+		levenSimilarityResults.Text1SubstringIndexes.StartIndex,
+		levenSimilarityResults.Text1SubstringIndexes.EndIndex,
+	)
+	if err != nil {
+		log.Printf("Error reading history of %s: %v", path, err)
+		return git_history.INCONCLUSIVE
+	}
+
+	sourceProvenance, err := challengeeHistory.FindRegionProvenance(
+		challengeePath, challengeeData,
+		levenSimilarityResults.Text2SubstringIndexes.StartIndex,
+		levenSimilarityResults.Text2SubstringIndexes.EndIndex,
+	)
+	if err != nil {
+		log.Printf("Error reading history of %s: %v", challengeePath, err)
+		return git_history.INCONCLUSIVE
+	}
+
+	return git_history.LabelCopyDirection(submissionProvenance, sourceProvenance)
+}
@@ -0,0 +1,220 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/base_code"
+	"hercules/src/code_parser"
+	"hercules/src/corpus_index"
+	"hercules/src/git_history"
+	"hercules/src/git_repo"
+	"hercules/src/tfidf"
+	"hercules/src/util"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// BuildCorpusIndex indexes the code files of the repos at repoDirs into a new index, saved to indexPath.
+// The LSH index of the signatures has bands of rows, see minhash.Index.
+func BuildCorpusIndex(repoDirs []string, indexPath string, bands int, rows int) error {
+	index := corpus_index.New(bands, rows)
+	if err := indexRepos(index, repoDirs); err != nil {
+		return err
+	}
+	fmt.Printf("Saving an index of %d repos and %d files to %s\n", len(index.Repos()), len(index.Files), indexPath)
+	return index.Save(indexPath)
+}
+
+// AddToCorpusIndex adds the repos at repoDirs to the index at indexPath. Repos already in it are updated:
+// changed files are indexed again and deleted files removed.
+func AddToCorpusIndex(repoDirs []string, indexPath string) error {
+	index, err := corpus_index.Load(indexPath)
+	if err != nil {
+		return err
+	}
+	if err := indexRepos(index, repoDirs); err != nil {
+		return err
+	}
+	fmt.Printf("Saving an index of %d repos and %d files to %s\n", len(index.Repos()), len(index.Files), indexPath)
+	return index.Save(indexPath)
+}
+
+// RemoveFromCorpusIndex removes the repos at repoDirs from the index at indexPath
+func RemoveFromCorpusIndex(repoDirs []string, indexPath string) error {
+	index, err := corpus_index.Load(indexPath)
+	if err != nil {
+		return err
+	}
+	for _, repoDir := range repoDirs {
+		absRepoDir, err := filepath.Abs(repoDir)
+		if err != nil {
+			return err
+		}
+		paths := index.RepoFiles(absRepoDir)
+		if len(paths) == 0 {
+			fmt.Printf("%s is not in the index\n", absRepoDir)
+			continue
+		}
+		for _, path := range paths {
+			index.RemoveFile(path)
+		}
+		fmt.Printf("Removed %d files of %s\n", len(paths), absRepoDir)
+	}
+	fmt.Printf("Saving an index of %d repos and %d files to %s\n", len(index.Repos()), len(index.Files), indexPath)
+	return index.Save(indexPath)
+}
+
+// indexRepos indexes the code files of every repo that aren't indexed with their content yet, and removes
+// the indexed files of the repos that are gone
+func indexRepos(index *corpus_index.Index, repoDirs []string) error {
+	hashes := index.Bands * index.Rows
+	for _, repoDir := range repoDirs {
+		absRepoDir, err := filepath.Abs(repoDir)
+		if err != nil {
+			return err
+		}
+		filePaths, err := util.GetFilePaths(absRepoDir)
+		if err != nil {
+			return err
+		}
+		filePaths = util.RemoveNonCodeFiles(filePaths)
+
+		indexed, unchanged := 0, 0
+		for batchStart := 0; batchStart < len(filePaths); batchStart += BACKGROUND_IDF_BATCH_SIZE {
+			batch := filePaths[batchStart:util.Min(batchStart+BACKGROUND_IDF_BATCH_SIZE, len(filePaths))]
+			allDataMap, err := util.MultipleFileRead(batch, TEXT_MAX_LENGTH)
+			if err != nil {
+				return err
+			}
+			for path, data := range allDataMap {
+				hash := corpus_index.Hash(data)
+				if index.IsIndexed(path, hash) {
+					unchanged++
+					continue
+				}
+				index.AddFile(absRepoDir, path, hash, tfidf.TokenizeCode(data), fileSignature(data, path, false, nil, hashes))
+				indexed++
+			}
+		}
+
+		present := make(map[string]bool, len(filePaths))
+		for _, path := range filePaths {
+			present[path] = true
+		}
+		removed := 0
+		for _, path := range index.RepoFiles(absRepoDir) {
+			if !present[path] {
+				index.RemoveFile(path)
+				removed++
+			}
+		}
+		fmt.Printf("Indexed %d files of %s, %d unchanged, %d removed\n", indexed, absRepoDir, unchanged, removed)
+	}
+	return nil
+}
+
+// scanCorpusIndex finds the files of the local corpus index with the search keywords of the file, or
+// with a similar MinHash signature, and compares the file with them like ParseCodeWorkflow does with the
+// files found on GitHub. The repos found are named by their directory.
+func scanCorpusIndex(
+	repoDir string,
+	path string,
+	codeText string,
+	baseCode *base_code.BaseCode,
+	options Options,
+	corpusIndex *corpus_index.Index,
+	keywordsTFIDF *tfidf.TFIDF,
+	keywordsTFIDFMutex *sync.Mutex,
+	charLevelTFIDF *tfidf.TFIDF,
+	charLevelTFIDFMutex *sync.Mutex,
+	possibleRepoMap map[string][]*MiniParseCodeWorkflowScanResult,
+	possibleRepoMapMutex *sync.Mutex,
+) int {
+	parsedCodeText := code_parser.ParseCode(codeText, path)
+	topKeywords := searchKeywords(path, codeText, baseCode, keywordsTFIDF, keywordsTFIDFMutex)
+	signature := fileSignature(codeText, path, false, baseCode, corpusIndex.Bands*corpusIndex.Rows)
+
+	var foundPaths []string
+	for _, foundPath := range append(corpusIndex.Search(topKeywords, NUMBER_OF_FILES_TO_QUERY), corpusIndex.Similar(signature, NUMBER_OF_FILES_TO_QUERY)...) {
+		// same language like the GitHub search, and never the submission itself
+		if util.Contains(foundPaths, foundPath) || !util.IsExtensionSame(path, foundPath) ||
+			corpusIndex.Files[foundPath].Repo == repoDir {
+			continue
+		}
+		foundPaths = append(foundPaths, foundPath)
+	}
+
+	resultChannel := make(chan *MiniParseCodeWorkflowScanResult, len(foundPaths))
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, runtime.NumCPU())
+	for _, foundPath := range foundPaths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(foundPath string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			data, err := os.ReadFile(foundPath)
+			if err != nil {
+				log.Printf("Could not read %s of the corpus index, add its repo again: %v", foundPath, err)
+				return
+			}
+			challengeeCodeText := string(data[:util.Min(TEXT_MAX_LENGTH, len(data))]) // to prevent OOM
+			result := scanFile(
+				path, codeText, parsedCodeText,
+				corpusIndex.Files[foundPath].Repo, foundPath, challengeeCodeText,
+				baseCode, options, charLevelTFIDF, charLevelTFIDFMutex,
+			)
+			if result == nil {
+				return
+			}
+			resultChannel <- result
+		}(foundPath)
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultChannel)
+		close(sem)
+	}()
+
+	return addScanResults(resultChannel, possibleRepoMap, possibleRepoMapMutex)
+}
+
+// isLocalRepo is true for the repos found in the corpus index, which are named by their absolute
+// directory instead of owner/repo
+func isLocalRepo(repoName string) bool {
+	return filepath.IsAbs(repoName)
+}
+
+// repoURL is the GitHub URL of a repo, or the directory of a local repo
+func repoURL(repoName string) string {
+	if isLocalRepo(repoName) {
+		return repoName
+	}
+	return git_repo.RepoURL(repoName)
+}
+
+// compareLocalRepo compares the submission with a repo of the corpus index where it is, like cloneAndCompare
+func compareLocalRepo(
+	challengeeDir string,
+	repoDir string,
+	allDataArray []string,
+	allDataMap map[string]string,
+	baseCode *base_code.BaseCode,
+	submissionHistory *git_history.History,
+	options Options,
+) *RepoToRepoHighestLikelihoodScores {
+	challengeeHistory, err := git_history.Open(challengeeDir)
+	if err != nil {
+		log.Printf("Error opening history of %s: %v", challengeeDir, err)
+		challengeeHistory = nil
+	}
+	return compareRepos(
+		repoDir, allDataArray, allDataMap,
+		challengeeDir, challengeeDir, challengeeDir,
+		baseCode, submissionHistory, challengeeHistory, options,
+	)
+}
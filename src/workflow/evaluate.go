@@ -0,0 +1,175 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/base_code"
+	"hercules/src/evaluation"
+	"hercules/src/score_fusion"
+	"hercules/src/util"
+	"os"
+)
+
+const METRIC_COMBINED = "combined"
+const METRIC_PROBABILITY = "probability"
+const METRIC_UNIT_COVERAGE = "unit_coverage"
+
+// EVALUATED_THRESHOLDS are the thresholds hercules uses for the metrics, reported next to the recommended ones
+var EVALUATED_THRESHOLDS = map[string]float64{
+	METRIC_CLNAT:         TFIDF_SIMILARITY_THRESHOLD,
+	METRIC_DAL:           LEVEN_SIMILARITY_THRESHOLD,
+	METRIC_WINNOWING:     WINNOWING_SIMILARITY_THRESHOLD,
+	METRIC_AST:           AST_SIMILARITY_THRESHOLD,
+	METRIC_GST:           GST_SIMILARITY_THRESHOLD,
+	METRIC_COMBINED:      COMBINED_SIMILARITY_THRESHOLD,
+	METRIC_PROBABILITY:   PROBABILITY_THRESHOLD,
+	METRIC_UNIT_COVERAGE: UNIT_COVERAGE_THRESHOLD,
+}
+
+// Evaluate runs the comparisons of hercules over the labeled pairs of the CSV file at datasetPath,
+// see score_fusion.ReadLabeledPairs, and reports how well every metric tells copies apart.
+// Pairs of directories are compared repo to repo, and pairs of files file to file.
+func Evaluate(datasetPath string, options Options) (*evaluation.Report, error) {
+	pairs, err := score_fusion.ReadLabeledPairs(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	if options.FusionModelPath != "" {
+		options.fusionModel, err = score_fusion.Load(options.FusionModelPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// pairs of files in two languages of a language family are reported apart, as their metrics compare
+	// language-neutral token streams
+	var filePairs, crossLanguagePairs, repoPairs []score_fusion.LabeledPair
+	for _, pair := range pairs {
+		isDir1, isDir2 := isDir(pair.Path1), isDir(pair.Path2)
+		if isDir1 != isDir2 {
+			return nil, fmt.Errorf("%s and %s must both be files or both be directories", pair.Path1, pair.Path2)
+		}
+		if isDir1 {
+			repoPairs = append(repoPairs, pair)
+		} else if isCrossLanguagePair(pair.Path1, pair.Path2, options) {
+			crossLanguagePairs = append(crossLanguagePairs, pair)
+		} else {
+			filePairs = append(filePairs, pair)
+		}
+	}
+
+	report := evaluation.Report{Dataset: datasetPath}
+	if len(filePairs) > 0 {
+		scores, err := evaluateFilePairs(filePairs, options)
+		if err != nil {
+			return nil, err
+		}
+		report.Levels = append(report.Levels, levelReport(evaluation.LEVEL_FILE, filePairs, scores, options))
+	}
+	if len(crossLanguagePairs) > 0 {
+		scores, err := evaluateFilePairs(crossLanguagePairs, options)
+		if err != nil {
+			return nil, err
+		}
+		report.Levels = append(report.Levels, levelReport(evaluation.LEVEL_CROSS_LANGUAGE, crossLanguagePairs, scores, options))
+	}
+	if len(repoPairs) > 0 {
+		scores, err := evaluateRepoPairs(repoPairs, options)
+		if err != nil {
+			return nil, err
+		}
+		report.Levels = append(report.Levels, levelReport(evaluation.LEVEL_REPO, repoPairs, scores, options))
+	}
+	return &report, nil
+}
+
+// evaluateFilePairs returns the scores of every metric for the pairs, by metric
+func evaluateFilePairs(pairs []score_fusion.LabeledPair, options Options) (map[string][]float64, error) {
+	comparisons, _, err := compareFilePairs(pairs, options)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string][]float64)
+	for _, comparison := range comparisons {
+		for _, metric := range ALL_METRICS {
+			scores[metric] = append(scores[metric], comparison.similarities[metric])
+		}
+		scores[METRIC_COMBINED] = append(scores[METRIC_COMBINED], comparison.combinedSimilarity)
+		scores[METRIC_PROBABILITY] = append(scores[METRIC_PROBABILITY], comparison.probability)
+	}
+	return scores, nil
+}
+
+// evaluateRepoPairs returns the weighted scores of every metric for the pairs, by metric,
+// comparing the first repo of every pair with the second like the repo-to-repo evaluation
+func evaluateRepoPairs(pairs []score_fusion.LabeledPair, options Options) (map[string][]float64, error) {
+	baseCode, err := base_code.Load(options.BaseSources)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string][]float64)
+	for i, pair := range pairs {
+		fmt.Printf("Comparing repo pair %d of %d: %s and %s\n", i+1, len(pairs), pair.Path1, pair.Path2)
+		allDataMap, err := readCodeFiles(pair.Path1)
+		if err != nil {
+			return nil, err
+		}
+		result := compareRepos(
+			pair.Path1, loadAllData(allDataMap), allDataMap,
+			pair.Path2, pair.Path2, pair.Path2,
+			baseCode, nil, nil, options,
+		)
+		if result == nil {
+			// too different in size to be compared, which hercules takes as not copied
+			result = &RepoToRepoHighestLikelihoodScores{}
+		}
+		scores[METRIC_CLNAT] = append(scores[METRIC_CLNAT], result.TFIDFSimilarityWeighted)
+		scores[METRIC_DAL] = append(scores[METRIC_DAL], result.LevenSimilarityWeighted)
+		scores[METRIC_WINNOWING] = append(scores[METRIC_WINNOWING], result.WinnowingSimilarityWeighted)
+		scores[METRIC_AST] = append(scores[METRIC_AST], result.ASTSimilarityWeighted)
+		scores[METRIC_GST] = append(scores[METRIC_GST], result.GSTSimilarityWeighted)
+		scores[METRIC_COMBINED] = append(scores[METRIC_COMBINED], result.CombinedSimilarityWeighted)
+		scores[METRIC_PROBABILITY] = append(scores[METRIC_PROBABILITY], result.ProbabilityWeighted)
+		scores[METRIC_UNIT_COVERAGE] = append(scores[METRIC_UNIT_COVERAGE], result.UnitCoverage)
+	}
+	return scores, nil
+}
+
+func levelReport(level string, pairs []score_fusion.LabeledPair, scores map[string][]float64, options Options) evaluation.LevelReport {
+	levelReport := evaluation.LevelReport{Level: level, Pairs: len(pairs)}
+	for _, pair := range pairs {
+		if pair.Label {
+			levelReport.Positives++
+		}
+	}
+
+	metrics := append(append([]string{}, ALL_METRICS...), METRIC_COMBINED)
+	if options.fusionModel != nil {
+		metrics = append(metrics, METRIC_PROBABILITY)
+	}
+	// only repos are split into functions
+	if _, ok := scores[METRIC_UNIT_COVERAGE]; ok {
+		metrics = append(metrics, METRIC_UNIT_COVERAGE)
+	}
+	for _, metric := range metrics {
+		samples := make([]evaluation.Sample, len(pairs))
+		for i, pair := range pairs {
+			samples[i] = evaluation.Sample{Score: scores[metric][i], Label: pair.Label, Obfuscation: pair.Obfuscation}
+		}
+		levelReport.Metrics = append(levelReport.Metrics, evaluation.EvaluateMetric(metric, samples, EVALUATED_THRESHOLDS[metric]))
+	}
+	return levelReport
+}
+
+// readCodeFiles reads the code files under dir, truncated to TEXT_MAX_LENGTH
+func readCodeFiles(dir string) (map[string]string, error) {
+	filePaths, err := util.GetFilePaths(dir)
+	if err != nil {
+		return nil, err
+	}
+	return util.MultipleFileRead(util.RemoveNonCodeFiles(filePaths), TEXT_MAX_LENGTH)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
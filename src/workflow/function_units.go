@@ -0,0 +1,97 @@
+package workflow
+
+import (
+	"hercules/src/base_code"
+	"hercules/src/code_units"
+	"hercules/src/util"
+	"log"
+	"strings"
+)
+
+const UNIT_COVERAGE_THRESHOLD = 0.5
+const UNIT_TEXT_MAX_LENGTH = 1000000 // a function after the first TEXT_MAX_LENGTH characters is as likely copied
+
+// MatchedUnit is a function of the submission and the most similar function of the challengee repo,
+// which can be in a file of another name
+type MatchedUnit struct {
+	ChallengerPath      string // relative to the submission directory
+	ChallengerName      string
+	ChallengerStartLine int
+	ChallengerEndLine   int
+	Path                string // relative to the challengee repo
+	Name                string
+	StartLine           int
+	EndLine             int
+	Similarity          float64
+}
+
+// matchFunctionUnits matches the functions of all files of the submission with those of all files of
+// the challengee repo, and returns the matches with the share of the submission's function lines
+// that were matched
+func matchFunctionUnits(
+	repoDir string,
+	allDataMap map[string]string,
+	challengeeDir string,
+	challengeeAllDataMap map[string]string,
+	baseCode *base_code.BaseCode,
+) ([]MatchedUnit, float64) {
+	units := extractUnits(readTruncatedFilesAgain(allDataMap), baseCode)
+	matches := code_units.MatchUnits(units, extractUnits(readTruncatedFilesAgain(challengeeAllDataMap), baseCode))
+
+	matchedUnits := make([]MatchedUnit, 0, len(matches))
+	for _, match := range matches {
+		matchedUnits = append(matchedUnits, MatchedUnit{
+			ChallengerPath:      relativePath(repoDir, match.Unit1.Path),
+			ChallengerName:      match.Unit1.Name,
+			ChallengerStartLine: match.Unit1.StartLine,
+			ChallengerEndLine:   match.Unit1.EndLine,
+			Path:                relativePath(challengeeDir, match.Unit2.Path),
+			Name:                match.Unit2.Name,
+			StartLine:           match.Unit2.StartLine,
+			EndLine:             match.Unit2.EndLine,
+			Similarity:          match.Similarity,
+		})
+	}
+	return matchedUnits, code_units.Coverage(units, matches)
+}
+
+// readTruncatedFilesAgain reads the files of dataMap that were truncated at TEXT_MAX_LENGTH again, up to
+// UNIT_TEXT_MAX_LENGTH, so the functions at their end are extracted too
+func readTruncatedFilesAgain(dataMap map[string]string) map[string]string {
+	var truncatedPaths []string
+	for path, data := range dataMap {
+		if len(data) >= TEXT_MAX_LENGTH {
+			truncatedPaths = append(truncatedPaths, path)
+		}
+	}
+	if len(truncatedPaths) == 0 {
+		return dataMap
+	}
+	untruncatedDataMap, err := util.MultipleFileRead(truncatedPaths, UNIT_TEXT_MAX_LENGTH)
+	if err != nil {
+		log.Printf("Error reading files again to extract their functions, using their first %d characters: %v", TEXT_MAX_LENGTH, err)
+		return dataMap
+	}
+	for path, data := range dataMap {
+		if _, ok := untruncatedDataMap[path]; !ok {
+			untruncatedDataMap[path] = data
+		}
+	}
+	return untruncatedDataMap
+}
+
+// extractUnits returns the functions of all files, in order of path, without those that are (nearly)
+// all base code
+func extractUnits(dataMap map[string]string, baseCode *base_code.BaseCode) []code_units.Unit {
+	var units []code_units.Unit
+	for _, path := range sortedPaths(dataMap) {
+		lines := strings.Split(dataMap[path], "\n")
+		for _, unit := range code_units.Extract(dataMap[path], path) {
+			if baseCode.IsBaseFile(strings.Join(lines[unit.StartLine-1:unit.EndLine], "\n")) {
+				continue
+			}
+			units = append(units, unit)
+		}
+	}
+	return units
+}
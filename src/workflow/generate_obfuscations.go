@@ -0,0 +1,159 @@
+package workflow
+
+import (
+	"encoding/csv"
+	"fmt"
+	"hercules/src/obfuscation"
+	"hercules/src/score_fusion"
+	"hercules/src/util"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const OBFUSCATED_ORIGINALS_DIR = "originals"
+const OBFUSCATED_VARIANTS_DIR = "variants"
+const OBFUSCATED_PAIRS_FILE = "pairs.csv"
+
+// GenerateObfuscatedPairs copies the supported code files of inputs, files or directories, to
+// outDir/originals, writes a variant of each with every obfuscation, and one with all of them, to
+// outDir/variants, and writes the labeled pairs to outDir/pairs.csv for hercules eval and train.
+// Every variant is paired with its original as copied, with the obfuscations that changed it, and
+// every original with another original of the same language as not copied.
+func GenerateObfuscatedPairs(inputs []string, outDir string, obfuscations []string, seed int64) error {
+	filePaths, err := obfuscationInputs(inputs)
+	if err != nil {
+		return err
+	}
+	if len(filePaths) == 0 {
+		return fmt.Errorf("no files to obfuscate in %s", strings.Join(inputs, ", "))
+	}
+	for _, dir := range []string{OBFUSCATED_ORIGINALS_DIR, OBFUSCATED_VARIANTS_DIR} {
+		if err := os.MkdirAll(filepath.Join(outDir, dir), 0755); err != nil {
+			return err
+		}
+	}
+
+	variantObfuscations := make([][]string, 0, len(obfuscations)+1)
+	for _, name := range obfuscations {
+		variantObfuscations = append(variantObfuscations, []string{name})
+	}
+	if len(obfuscations) > 1 {
+		variantObfuscations = append(variantObfuscations, obfuscations)
+	}
+
+	random := rand.New(rand.NewSource(seed))
+	records := [][]string{{"file1", "file2", "label", "obfuscation"}}
+	variantCounts := make(map[string]int)
+	originalsByExtension := make(map[string][]string)
+	var originals []string
+	for i, filePath := range filePaths {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		text := string(content)
+		extension := filepath.Ext(filePath)
+		name := fmt.Sprintf("%04d_%s", i+1, strings.TrimSuffix(filepath.Base(filePath), extension))
+
+		original := filepath.Join(OBFUSCATED_ORIGINALS_DIR, name+extension)
+		if err := writeObfuscatedFile(outDir, original, text); err != nil {
+			return err
+		}
+		originals = append(originals, original)
+		originalsByExtension[extension] = append(originalsByExtension[extension], original)
+
+		for _, variant := range variantObfuscations {
+			files, appliedList := obfuscation.Obfuscate(text, filePath, variant, random)
+			if len(appliedList) == 0 {
+				continue
+			}
+			applied := strings.Join(appliedList, "+")
+			variantCounts[applied]++
+			for part, file := range files {
+				suffix := applied
+				if len(variant) > 1 {
+					suffix = "all"
+				}
+				if len(files) > 1 {
+					suffix = fmt.Sprintf("%s.part%d", suffix, part+1)
+				}
+				variantPath := filepath.Join(OBFUSCATED_VARIANTS_DIR, name+"."+suffix+extension)
+				if err := writeObfuscatedFile(outDir, variantPath, file); err != nil {
+					return err
+				}
+				records = append(records, []string{filepath.ToSlash(variantPath), filepath.ToSlash(original), score_fusion.LABEL_COPIED, applied})
+			}
+		}
+	}
+
+	for _, original := range originals {
+		others := originalsByExtension[filepath.Ext(original)]
+		if len(others) < 2 {
+			continue
+		}
+		other := others[random.Intn(len(others))]
+		for other == original {
+			other = others[random.Intn(len(others))]
+		}
+		records = append(records, []string{filepath.ToSlash(original), filepath.ToSlash(other), score_fusion.LABEL_NOT_COPIED, ""})
+	}
+
+	pairsPath := filepath.Join(outDir, OBFUSCATED_PAIRS_FILE)
+	file, err := os.Create(pairsPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+
+	fmt.Printf("Obfuscated %d files into %d labeled pairs in %s, with the variants by obfuscations:\n", len(filePaths), len(records)-1, pairsPath)
+	var applied []string
+	for obfuscations := range variantCounts {
+		applied = append(applied, obfuscations)
+	}
+	sort.Strings(applied)
+	for _, obfuscations := range applied {
+		fmt.Printf("  %s: %d\n", obfuscations, variantCounts[obfuscations])
+	}
+	return nil
+}
+
+// obfuscationInputs returns the files of inputs, and the code files under the directories of inputs,
+// whose language can be obfuscated, in order
+func obfuscationInputs(inputs []string) ([]string, error) {
+	var filePaths []string
+	for _, input := range inputs {
+		if !isDir(input) {
+			if _, err := os.Stat(input); err != nil {
+				return nil, err
+			}
+			filePaths = append(filePaths, input)
+			continue
+		}
+		dirFilePaths, err := util.GetFilePaths(input)
+		if err != nil {
+			return nil, err
+		}
+		dirFilePaths = util.RemoveNonCodeFiles(dirFilePaths)
+		sort.Strings(dirFilePaths)
+		filePaths = append(filePaths, dirFilePaths...)
+	}
+
+	var supported []string
+	for _, filePath := range filePaths {
+		if obfuscation.IsSupported(filePath) {
+			supported = append(supported, filePath)
+		}
+	}
+	return supported, nil
+}
+
+func writeObfuscatedFile(outDir string, path string, text string) error {
+	return os.WriteFile(filepath.Join(outDir, path), []byte(text), 0644)
+}
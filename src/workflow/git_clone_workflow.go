@@ -10,7 +10,7 @@ import (
 	"syscall"
 )
 
-func RunGitCloneWorkflow(repoUrl string) error {
+func RunGitCloneWorkflow(repoUrl string, options Options) error {
 	// Create a temporary directory
 	dir, err := os.MkdirTemp("", util.TEMP_REPO_PREFIX)
 	if err != nil {
@@ -36,6 +36,6 @@ func RunGitCloneWorkflow(repoUrl string) error {
 	if err != nil {
 		return fmt.Errorf("error getting repo name from URL: %v", err)
 	}
-	RunWorkflow(dir, repoName, true)
+	RunWorkflow(dir, repoName, true, options)
 	return nil
 }
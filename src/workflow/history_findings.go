@@ -0,0 +1,95 @@
+package workflow
+
+import (
+	"hercules/src/git_history"
+	"hercules/src/git_repo"
+	"hercules/src/util"
+	"log"
+	"strings"
+)
+
+const SHARED_ROOT_DESCRIPTION = "also the root commit of "
+
+// analyseSubmissionHistory runs the anomaly detection over the submission's history
+// and looks up its root commits on GitHub, unless only the corpus is searched
+func analyseSubmissionHistory(
+	submissionHistory *git_history.History,
+	submissionRepoName string,
+	options Options,
+) []git_history.HistoryFinding {
+	if submissionHistory == nil {
+		return nil
+	}
+
+	findings, err := submissionHistory.DetectAnomalies(git_history.AnomalyOptions{
+		StudentEmails: options.StudentEmails,
+		ReleaseDate:   options.ReleaseDate,
+	})
+	if err != nil {
+		log.Printf("Error analysing the submission's history: %v", err)
+	}
+	if options.SkipGitHubSearch {
+		return findings
+	}
+
+	roots, err := submissionHistory.RootCommits()
+	if err != nil {
+		log.Printf("Error reading the submission's root commits: %v", err)
+		return findings
+	}
+	for _, root := range roots {
+		result, err := git_repo.SearchCommitsByHash(root)
+		if err != nil {
+			log.Printf("Error searching GitHub for root commit %s: %v", root, err)
+			continue
+		}
+		var publicRepos []string
+		for _, item := range result.Items {
+			if strings.EqualFold(item.Repository.FullName, submissionRepoName) {
+				continue
+			}
+			publicRepos = append(publicRepos, item.Repository.FullName)
+		}
+		if len(publicRepos) > 0 {
+			findings = append(findings, git_history.HistoryFinding{
+				Kind:        git_history.FINDING_SHARED_ROOT,
+				Commit:      root,
+				Description: SHARED_ROOT_DESCRIPTION + strings.Join(publicRepos, ", "),
+			})
+		}
+	}
+	return findings
+}
+
+// sharedRootCommitFindings adds the candidate repos that share a root commit with the submission to the
+// findings, one finding per root commit, as analyseSubmissionHistory may have found it on GitHub already
+func sharedRootCommitFindings(
+	findings []git_history.HistoryFinding,
+	highlyLikelyRepos []RepoToRepoHighestLikelihoodScores,
+) []git_history.HistoryFinding {
+	findings = append([]git_history.HistoryFinding{}, findings...)
+	for _, repo := range highlyLikelyRepos {
+		if repo.SharedRootCommit == "" {
+			continue
+		}
+		found := false
+		for i, finding := range findings {
+			if finding.Kind != git_history.FINDING_SHARED_ROOT || finding.Commit != repo.SharedRootCommit {
+				continue
+			}
+			found = true
+			repoNames := strings.Split(strings.TrimPrefix(finding.Description, SHARED_ROOT_DESCRIPTION), ", ")
+			if !util.Contains(repoNames, repo.RepoName) {
+				findings[i].Description += ", " + repo.RepoName
+			}
+		}
+		if !found {
+			findings = append(findings, git_history.HistoryFinding{
+				Kind:        git_history.FINDING_SHARED_ROOT,
+				Commit:      repo.SharedRootCommit,
+				Description: SHARED_ROOT_DESCRIPTION + repo.RepoName,
+			})
+		}
+	}
+	return findings
+}
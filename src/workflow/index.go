@@ -2,17 +2,24 @@ package workflow
 
 import (
 	"fmt"
-	"hercules/src/code_parser"
+	"hercules/src/assignment"
+	"hercules/src/base_code"
+	"hercules/src/corpus_index"
+	"hercules/src/git_history"
 	"hercules/src/git_repo"
+	"hercules/src/minhash"
+	"hercules/src/score_fusion"
 	"hercules/src/similarity_compute"
 	"hercules/src/tfidf"
 	"hercules/src/util"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sort"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
@@ -24,36 +31,113 @@ const TEXT_MAX_LENGTH = 25000
 const NO_OF_FILES_FOR_PARSING = 18
 const NO_OF_MAX_SEARCHED_FILES_TO_PARSE = 180 // can be set if you want to parse less files
 
-type RepoToRepoPotentialChallengeeData struct {
-	path            string
-	data            string
-	tfidfSimilarity float64
-}
-
 type RepoToRepoMatchedChallengeeData struct {
-	NumberOfLinesCopied int
-	Path                string
-	TFIDFSimilarity     float64
-	LevenSimilarity     float64
-	CombinedSimilarity  float64
+	NumberOfLinesCopied      int     // non-blank lines of the file in a copied segment, or in the DAL substring if none
+	NumberOfCharactersCopied int     // bytes of the copied segments, or of the DAL substring
+	CopiedLinesShare         float64 // share of the file's non-blank lines copied
+	ChallengerPath           string  // relative to the submission directory
+	Path                     string  // relative to the challengee repo
+	TFIDFSimilarity          float64
+	LevenSimilarity          float64
+	LevenStartLine1          int // lines of the DAL substring in the file, the end inclusive
+	LevenEndLine1            int
+	LevenStartLine2          int // and in the matched file
+	LevenEndLine2            int
+	WinnowingSimilarity      float64
+	WinnowingCoverage1       float64 // share of the file's fingerprints found in the matched file
+	WinnowingCoverage2       float64 // and vice versa
+	WinnowingMatchedKGrams   []similarity_compute.MatchedKGram
+	ASTSimilarity            float64
+	ASTMatchedSubtrees       []similarity_compute.MatchedSubtree
+	GSTSimilarity            float64
+	GSTMatchedTiles          []similarity_compute.MatchedTile
+	CopiedSegments           []similarity_compute.CopiedSegment
+	SegmentCoverage1         float64 // share of the file's significant lines in a copied segment
+	SegmentCoverage2         float64 // and of the matched file's
+	CombinedSimilarity       float64
+	Probability              float64 // of the fusion model, 0 without one
+	BaseCodeShare            float64
+	CopyDirection            string
+	CrossLanguage            bool // compared by the language-neutral token streams, as the files are in different languages
 }
 
 type RepoToRepoHighestLikelihoodScores struct {
-	RepoUrl                    string
-	RepoName                   string
-	TotalNumberOfFiles         int
-	SimilarNumberOfFiles       int
-	TFIDFSimilarityWeighted    float64
-	LevenSimilarityWeighted    float64
-	CombinedSimilarityWeighted float64
+	RepoUrl                         string
+	RepoName                        string
+	TotalNumberOfFiles              int
+	SimilarNumberOfFiles            int
+	TFIDFSimilarityWeighted         float64
+	LevenSimilarityWeighted         float64
+	WinnowingSimilarityWeighted     float64
+	ASTSimilarityWeighted           float64
+	GSTSimilarityWeighted           float64
+	CombinedSimilarityWeighted      float64
+	ProbabilityWeighted             float64 // weighted average of the probabilities of the matched files, not a probability the repo was copied
+	BaseCodeShareWeighted           float64
+	CrossLanguageNumberOfFiles      int                               // matched files in another language, not counted in the scores above
+	CrossLanguageSimilarityWeighted float64                           // combined similarity of those, weighted by lines copied
+	UnitCoverage                    float64                           // share of the submission's function lines in a matched function, only set for the repo-to-repo evaluation
+	MatchedFiles                    []RepoToRepoMatchedChallengeeData // only set for the repo-to-repo evaluation
+	MatchedUnits                    []MatchedUnit                     // only set for the repo-to-repo evaluation
+	SharedRootCommit                string                            // root commit the challengee shares with the submission, if any
 }
 
 const TFIDF_SIMILARITY_THRESHOLD = 0.7
+const CROSS_LANGUAGE_TFIDF_SIMILARITY_THRESHOLD = 0.4 // the neutral token streams of two languages differ more
 const LEVEN_SIMILARITY_THRESHOLD = 0.7
+const WINNOWING_SIMILARITY_THRESHOLD = 0.5
+const AST_SIMILARITY_THRESHOLD = 0.7
+const GST_SIMILARITY_THRESHOLD = 0.5
+const PROBABILITY_THRESHOLD = 0.5
 const COMBINED_SIMILARITY_THRESHOLD = 0.4
 const CHOOSE_TOP_N_REPOS = 8
 
-func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
+// the amount of copied code the scores of the matched files are weighted by
+const (
+	WEIGHT_BY_CHARACTERS = "characters" // bytes copied
+	WEIGHT_BY_LINES      = "lines"      // non-blank lines copied, so long lines don't weigh more
+)
+
+var WEIGHTINGS = []string{WEIGHT_BY_CHARACTERS, WEIGHT_BY_LINES}
+
+// Options are the user settings of a workflow run
+type Options struct {
+	SubmissionRepo    string     // owner/repo of the submission on GitHub, if known, used to find its fork network
+	Submitter         string     // owner whose repos are never candidates, defaults to the submission's owner
+	ExcludedOwners    []string   // allowlisted owners whose repos are never candidates, e.g. the course org
+	StudentEmails     []string   // author emails of the student, commits by anyone else are flagged
+	ReleaseDate       time.Time  // when the assignment was released, commits before are flagged
+	BaseSources       []string   // directories or GitHub URLs of the starter code, which is discounted
+	CombinedMetrics   []string   // metrics multiplied into the combined similarity, DEFAULT_COMBINED_METRICS if empty
+	GSTMinimumMatch   int        // minimum tile length in tokens for greedy string tiling, GST_MINIMUM_MATCH_LENGTH if 0
+	IDFModelPath      string     // background IDF model built with BuildBackgroundIDF, for more distinctive search keywords
+	CLNATTokenizer    string     // tokenizer of the CLNAT TFIDF models, see tfidf.ParseTokenizer, tfidf.DEFAULT_TOKENIZER if empty
+	FusionModelPath   string     // score fusion model trained with TrainFusionModel, for the plagiarism probability of the matches
+	MaxMatchesPerFile int        // files of the submission a file of a challengee repo can be matched with, 1 if 0
+	LanguageFamilies  [][]string // extensions of the languages files are compared across, none if empty
+	LSHBands          int        // bands of the MinHash LSH index that picks the pairs of files to compare, every pair if 0
+	LSHRows           int        // rows of every band
+	CorpusIndexPath   string     // local corpus index built with BuildCorpusIndex, searched for candidate repos too
+	SkipGitHubSearch  bool       // only search the corpus index
+	WeightBy          string     // how the weighted scores weigh the matched files, WEIGHT_BY_CHARACTERS if empty
+
+	fusionModel *score_fusion.Model // loaded from FusionModelPath by RunWorkflow
+}
+
+// DefaultOptions are the defaults of the flags. Their LSH bands and rows only apply with --lsh, the
+// commands set LSHBands to 0 without it.
+func DefaultOptions() Options {
+	return Options{
+		GSTMinimumMatch:   similarity_compute.GST_MINIMUM_MATCH_LENGTH,
+		CLNATTokenizer:    tfidf.DEFAULT_TOKENIZER,
+		MaxMatchesPerFile: 1,
+		LSHBands:          minhash.DEFAULT_BANDS,
+		LSHRows:           minhash.DEFAULT_ROWS,
+		WeightBy:          WEIGHT_BY_CHARACTERS,
+	}
+}
+
+func RunWorkflow(repoDir string, repoName string, isTempDir bool, options Options) {
 	filePaths, err := util.GetFilePaths(repoDir)
 	util.Check(err)
 
@@ -69,16 +153,51 @@ func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
 
 	fmt.Printf("Number of files: %d\n", len(allDataArray))
 
+	baseCode, err := base_code.Load(options.BaseSources)
+	if err != nil {
+		fmt.Printf("Error loading base code: %v\n", err)
+		os.Exit(1)
+	}
+	if options.FusionModelPath != "" {
+		options.fusionModel, err = score_fusion.Load(options.FusionModelPath)
+		if err != nil {
+			fmt.Printf("Error loading fusion model: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// the tfidf corpora are built without the base code, so its terms don't count
+	tfidfDataArray := util.Map(allDataArray, baseCode.StripBaseLines)
+
+	submissionHistory, err := git_history.Open(repoDir)
+	if err != nil {
+		fmt.Println("No git history found for the submission, copy direction will be inconclusive.")
+		submissionHistory = nil
+	}
+
 	// create a char level tfidf with the files
-	charLevelTFIDF := tfidf.New()
-	charLevelTFIDF.AddDocs(allDataArray, tfidf.TokenizeCharLevelNoAlpha)
+	charLevelTFIDF := newCLNATTFIDF(options)
+	charLevelTFIDF.AddDocs(tfidfDataArray)
 	charLevelTFIDFMutex := &sync.Mutex{}
 
-	// create normal tfidf with the files
-	keywordsTFIDF := tfidf.New()
-	keywordsTFIDF.AddDocs(allDataArray)
+	// create normal tfidf with the files, on top of the background model if there is one,
+	// so the keywords are the terms that are rare in code in general, not only in this repo
+	keywordsTFIDF := tfidf.NewForKeywords()
+	if options.IDFModelPath != "" {
+		backgroundTFIDF, err := tfidf.Load(options.IDFModelPath)
+		if err != nil {
+			fmt.Printf("Error loading IDF model: %v\n", err)
+			os.Exit(1)
+		}
+		keywordsTFIDF.Merge(backgroundTFIDF)
+	}
+	keywordsTFIDF.AddDocs(tfidfDataArray)
 	keywordsTFIDFMutex := &sync.Mutex{}
 
+	// files that are only base code can't have been copied
+	filePaths = util.Filter(filePaths, func(path string) bool {
+		return !baseCode.IsBaseFile(allDataMap[path])
+	})
+
 	// randomly draw half that is code files first
 	codeFilePaths := util.Filter(filePaths, func(path string) bool {
 		return util.IsCodeFile(path)
@@ -93,6 +212,17 @@ func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
 	randomlyDrawnFilesN := util.RandomDrawWithoutReplacement(remainingFilePaths, NO_OF_FILES_FOR_PARSING-len(codeFilePaths))
 	randomlyDrawnFilesN = append(randomlyDrawnFilesN, codeFilePaths...)
 
+	// repos on disk, e.g. earlier submissions, are searched alongside GitHub or instead of it
+	var corpusIndex *corpus_index.Index
+	if options.CorpusIndexPath != "" {
+		corpusIndex, err = corpus_index.Load(options.CorpusIndexPath)
+		if err != nil {
+			fmt.Printf("Error loading corpus index: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Searching a corpus index of %d files of %d repos\n", len(corpusIndex.Files), len(corpusIndex.Repos()))
+	}
+
 	// for each file, parse
 	possibleRepoMap := make(map[string][]*MiniParseCodeWorkflowScanResult)
 	possibleRepoMapMutex := &sync.Mutex{}
@@ -124,17 +254,33 @@ func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
 		count := 0
 		for _, path := range randomlyDrawnFilesN {
 			// dont need to goroutine since github has a rate limit
-			numberOfFilesParsed, _ := ParseCodeWorkflow(
-				repoName,
-				path, isTempDir, allDataMap[path],
-				keywordsTFIDF, keywordsTFIDFMutex,
-				charLevelTFIDF, charLevelTFIDFMutex,
-				possibleRepoMap, possibleRepoMapMutex,
-			)
+			numberOfFilesParsed := 0
+			if !options.SkipGitHubSearch {
+				numberOfFilesParsed, _ = ParseCodeWorkflow(
+					repoName,
+					path, isTempDir, allDataMap[path], baseCode, options,
+					keywordsTFIDF, keywordsTFIDFMutex,
+					charLevelTFIDF, charLevelTFIDFMutex,
+					possibleRepoMap, possibleRepoMapMutex,
+				)
+			}
+			if corpusIndex != nil {
+				numberOfFilesParsed += scanCorpusIndex(
+					repoDir,
+					path, allDataMap[path], baseCode, options, corpusIndex,
+					keywordsTFIDF, keywordsTFIDFMutex,
+					charLevelTFIDF, charLevelTFIDFMutex,
+					possibleRepoMap, possibleRepoMapMutex,
+				)
+			}
 			count++
 			totalNumberOfFilesParsed = util.Min(totalNumberOfFilesParsed+numberOfFilesParsed, maxNumberOfSearchedFilesToBeParsed)
 			fileSearchProgressBarModel.Send(progressMsg{workflowsDone: count})
-			fileSearchProgressBarModel.Send(updateMessageMsg{message: fmt.Sprintf("Parsing %s... (Might slow down due to Github API Rate Limit)", path)})
+			if options.SkipGitHubSearch {
+				fileSearchProgressBarModel.Send(updateMessageMsg{message: fmt.Sprintf("Parsing %s...", path)})
+			} else {
+				fileSearchProgressBarModel.Send(updateMessageMsg{message: fmt.Sprintf("Parsing %s... (Might slow down due to Github API Rate Limit)", path)})
+			}
 			if totalNumberOfFilesParsed >= maxNumberOfSearchedFilesToBeParsed {
 				break
 			}
@@ -155,9 +301,17 @@ func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
 	// EVALUATE REPOSITORIES //
 	///////////////////////////
 
+	submissionRepoName := options.SubmissionRepo
+	if submissionRepoName == "" && isTempDir {
+		submissionRepoName = repoName
+	}
+	possibleRepoMap, excludedCandidates := filterCandidateRepos(submissionRepoName, possibleRepoMap, options)
+	historyFindings := analyseSubmissionHistory(submissionHistory, submissionRepoName, options)
 	possibleReposTopN := getTopNRepos(possibleRepoMap, CHOOSE_TOP_N_REPOS)
 
 	if len(possibleReposTopN) == 0 {
+		RenderExcludedCandidatesTable(excludedCandidates)
+		RenderHistoryFindingsTable(historyFindings)
 		fmt.Println("No repositories found, hence no plagiarism detected!")
 		os.Exit(0)
 	}
@@ -171,27 +325,22 @@ func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
 	var preliminaryHighlyLikelyRepos []RepoToRepoHighestLikelihoodScores
 
 	for challengeeRepoName, challengeeRepoData := range possibleReposTopNMap {
-		totalNumberOfLinesCopied := 0
-		for _, data := range challengeeRepoData {
-			totalNumberOfLinesCopied += data.NumberOfLinesCopied
-		}
 		result := computePreliminarySimilarityScoresWeighted(
 			len(allDataArray),
-			totalNumberOfLinesCopied,
 			challengeeRepoData,
 			challengeeRepoName,
+			options.WeightBy,
 		)
 		preliminaryHighlyLikelyRepos = append(preliminaryHighlyLikelyRepos, *result)
 	}
 
-	// sort by combined similarity, descending order
-	sort.Slice(preliminaryHighlyLikelyRepos, func(i, j int) bool {
-		return preliminaryHighlyLikelyRepos[i].CombinedSimilarityWeighted > preliminaryHighlyLikelyRepos[j].CombinedSimilarityWeighted
-	})
+	sortByLikelihood(preliminaryHighlyLikelyRepos, options)
 
 	fmt.Println("-----------------------------------")
 	fmt.Println("Preliminary Results")
 	RenderTable(repoName, preliminaryHighlyLikelyRepos)
+	RenderExcludedCandidatesTable(excludedCandidates)
+	RenderHistoryFindingsTable(historyFindings)
 	fmt.Println("-----------------------------------")
 	// ask user if want to continue advanced repo-to-repo match evaluation
 	fmt.Println("Do you want to continue to advanced repo-to-repo match evaluation? (y/n)")
@@ -218,7 +367,12 @@ func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
 		countOfDone := 0
 		for _, challengeeRepoName := range possibleReposTopN {
 			repoEvaluationProgressBarModel.Send(updateMessageMsg{message: fmt.Sprintf("Evaluating repo number %d...", countOfDone)})
-			result := cloneAndCompare(challengeeRepoName, allDataArray, allDataMap)
+			var result *RepoToRepoHighestLikelihoodScores
+			if isLocalRepo(challengeeRepoName) {
+				result = compareLocalRepo(challengeeRepoName, repoDir, allDataArray, allDataMap, baseCode, submissionHistory, options)
+			} else {
+				result = cloneAndCompare(challengeeRepoName, repoDir, allDataArray, allDataMap, baseCode, submissionHistory, options)
+			}
 			if result != nil {
 				highlyLikelyRepos = append(highlyLikelyRepos, *result)
 			}
@@ -233,12 +387,23 @@ func RunWorkflow(repoDir string, repoName string, isTempDir bool) {
 		os.Exit(1)
 	}
 
-	// sort by combined similarity, descending order
-	sort.Slice(highlyLikelyRepos, func(i, j int) bool {
-		return highlyLikelyRepos[i].CombinedSimilarityWeighted > highlyLikelyRepos[j].CombinedSimilarityWeighted
-	})
+	sortByLikelihood(highlyLikelyRepos, options)
 	RenderTable(repoName, highlyLikelyRepos)
+	RenderMatchedFilesTable(highlyLikelyRepos)
+	RenderMatchedUnitsTable(highlyLikelyRepos)
+	RenderHistoryFindingsTable(sharedRootCommitFindings(historyFindings, highlyLikelyRepos))
+}
 
+// newCLNATTFIDF creates an empty CLNAT model with the tokenizer of the options,
+// which it uses for both the documents and the files compared
+func newCLNATTFIDF(options Options) *tfidf.TFIDF {
+	tokenizerName := options.CLNATTokenizer
+	if tokenizerName == "" {
+		tokenizerName = tfidf.DEFAULT_TOKENIZER
+	}
+	tokenize, err := tfidf.ParseTokenizer(tokenizerName)
+	util.Check(err)
+	return tfidf.NewTokenizerFunc(tokenize)
 }
 
 func loadAllData(allDataMap map[string]string) []string {
@@ -250,8 +415,16 @@ func loadAllData(allDataMap map[string]string) []string {
 	return allDataArray
 }
 
-func cloneAndCompare(challengeeRepoName string, allDataArray []string, allDataMap map[string]string) *RepoToRepoHighestLikelihoodScores {
-	challengeeRepoUrl := "https://github.com/" + challengeeRepoName
+func cloneAndCompare(
+	challengeeRepoName string,
+	repoDir string,
+	allDataArray []string,
+	allDataMap map[string]string,
+	baseCode *base_code.BaseCode,
+	submissionHistory *git_history.History,
+	options Options,
+) *RepoToRepoHighestLikelihoodScores {
+	challengeeRepoUrl := git_repo.RepoURL(challengeeRepoName)
 	challengeeDir, err := os.MkdirTemp("", util.TEMP_REPO_PREFIX)
 	if err != nil {
 		log.Fatalf("Error creating temp directory: %v", err)
@@ -271,6 +444,33 @@ func cloneAndCompare(challengeeRepoName string, allDataArray []string, allDataMa
 	}()
 
 	git_repo.GitClone(challengeeRepoUrl, challengeeDir)
+	challengeeHistory, err := git_history.Open(challengeeDir)
+	if err != nil {
+		log.Printf("Error opening history of %s: %v", challengeeRepoName, err)
+		challengeeHistory = nil
+	}
+	return compareRepos(
+		repoDir, allDataArray, allDataMap,
+		challengeeRepoName, challengeeRepoUrl, challengeeDir,
+		baseCode, submissionHistory, challengeeHistory, options,
+	)
+}
+
+// compareRepos matches the files of the submission at repoDir with the files of the challengee repo at
+// challengeeDir, so that the matched pairs are the most similar overall, and weighs their similarities. It returns nil if one repo has
+// more than twice the files of the other.
+func compareRepos(
+	repoDir string,
+	allDataArray []string,
+	allDataMap map[string]string,
+	challengeeRepoName string,
+	challengeeRepoUrl string,
+	challengeeDir string,
+	baseCode *base_code.BaseCode,
+	submissionHistory *git_history.History,
+	challengeeHistory *git_history.History,
+	options Options,
+) *RepoToRepoHighestLikelihoodScores {
 	filePaths, err := util.GetFilePaths(challengeeDir)
 	util.Check(err)
 
@@ -290,132 +490,304 @@ func cloneAndCompare(challengeeRepoName string, allDataArray []string, allDataMa
 		return nil
 	}
 	// create a char level tfidf with the files
-	combinedCharLevelTFIDF := tfidf.New()
-	combinedCharLevelTFIDF.AddDocs(challengeeAllDataArray, tfidf.TokenizeCharLevelNoAlpha)
-	combinedCharLevelTFIDF.AddDocs(allDataArray, tfidf.TokenizeCharLevelNoAlpha)
+	combinedCharLevelTFIDF := newCLNATTFIDF(options)
+	combinedCharLevelTFIDF.AddDocs(util.Map(challengeeAllDataArray, baseCode.StripBaseLines))
+	combinedCharLevelTFIDF.AddDocs(util.Map(allDataArray, baseCode.StripBaseLines))
+
+	paths := sortedPaths(allDataMap)
+	challengeePaths := sortedPaths(challengeeAllDataMap)
+	candidates := candidatePairs(paths, allDataMap, challengeePaths, challengeeAllDataMap, baseCode, options)
+
+	// the TFIDF weights of only the files of a candidate pair
+	charLevelWeights := make(map[string]map[string]float64)
+	charLevelWeight := func(path string, data string) map[string]float64 {
+		if _, ok := charLevelWeights[path]; !ok {
+			charLevelWeights[path] = combinedCharLevelTFIDF.Cal(baseCode.StripBaseLines(data))
+		}
+		return charLevelWeights[path]
+	}
+	// and of the language-neutral token streams of the files of a cross-language candidate pair
+	var crossLanguagePaths []string
+	for _, candidate := range candidates {
+		if path, challengeePath := paths[candidate.index], challengeePaths[candidate.challengeeIndex]; isCrossLanguagePair(path, challengeePath, options) {
+			crossLanguagePaths = append(crossLanguagePaths, path, challengeePath)
+		}
+	}
+	var neutralWeights map[string]map[string]float64
+	if len(crossLanguagePaths) > 0 {
+		neutralWeights = neutralTFIDFWeights([]map[string]string{allDataMap, challengeeAllDataMap}, crossLanguagePaths, baseCode)
+	}
 
-	matchedMap := make(map[string]RepoToRepoMatchedChallengeeData) // map[challengePath]RepoToRepoMatchedChallengeeData
-	tempMemory := make(map[string](map[string]float64))
-
-	// for each file, find the best challengee file to match with
-	for path, data := range allDataMap {
-		w1 := combinedCharLevelTFIDF.Cal(data)
-		var challengeeArray []RepoToRepoPotentialChallengeeData
-		for challengeePath, challengeeData := range challengeeAllDataMap {
-			if !util.IsExtensionSame(path, challengeePath) {
+	// the CLNAT similarity of the candidate pairs above the threshold of their kind
+	var weightedPairs []assignment.Pair
+	tfidfSimilarities := make(map[filePair]float64)
+	for _, candidate := range candidates {
+		path, challengeePath := paths[candidate.index], challengeePaths[candidate.challengeeIndex]
+		var tfidfSimilarity float64
+		if isCrossLanguagePair(path, challengeePath, options) {
+			tfidfSimilarity = similarity.Cosine(neutralWeights[path], neutralWeights[challengeePath])
+			if tfidfSimilarity <= CROSS_LANGUAGE_TFIDF_SIMILARITY_THRESHOLD {
 				continue
 			}
-			w2, ok := tempMemory[challengeePath]
-			if !ok {
-				w2 = combinedCharLevelTFIDF.Cal(challengeeData)
-				tempMemory[challengeePath] = w2
-			}
-			similarity := similarity.Cosine(w1, w2)
-			if similarity > TFIDF_SIMILARITY_THRESHOLD {
-				obj := RepoToRepoPotentialChallengeeData{
-					path:            challengeePath,
-					data:            challengeeData,
-					tfidfSimilarity: similarity,
-				}
-				challengeeArray = append(challengeeArray, obj)
-				break
+		} else {
+			tfidfSimilarity = similarity.Cosine(
+				charLevelWeight(path, allDataMap[path]),
+				charLevelWeight(challengeePath, challengeeAllDataMap[challengeePath]),
+			)
+			if tfidfSimilarity <= TFIDF_SIMILARITY_THRESHOLD {
+				continue
 			}
 		}
-		if len(challengeeArray) > 0 {
-			// get the challengee with the highest similarity with sort
-			sort.Slice(challengeeArray, func(i, j int) bool {
-				return challengeeArray[i].tfidfSimilarity > challengeeArray[j].tfidfSimilarity
-			})
-			mostMatchedChallengeeData := challengeeArray[0]
-
-			challengerParsedCodeText := code_parser.ParseCodeText(data)
-			challengeeParsedCodeText := code_parser.ParseCodeText(mostMatchedChallengeeData.data)
-
-			levenSimilarityResults := similarity_compute.ComputeLevenSimilarity(
-				challengerParsedCodeText,
-				challengeeParsedCodeText,
-			)
+		weightedPairs = append(weightedPairs, assignment.Pair{
+			Row: candidate.index, Column: candidate.challengeeIndex, Weight: tfidfSimilarity,
+		})
+		tfidfSimilarities[candidate] = tfidfSimilarity
+	}
+	charLevelWeights = nil // free memory
+	neutralWeights = nil
 
-			combinedSimilarity := levenSimilarityResults.Percentage * mostMatchedChallengeeData.tfidfSimilarity
+	// every file is matched with at most one challengee file, and every challengee file with at most
+	// MaxMatchesPerFile files, so that the matched pairs are the most similar overall
+	assigned := assignment.AssignPairs(weightedPairs, len(paths), 0, options.MaxMatchesPerFile)
 
-			matchedMap[path] = RepoToRepoMatchedChallengeeData{
-				NumberOfLinesCopied: levenSimilarityResults.Text1SubstringIndexes.EndIndex -
-					levenSimilarityResults.Text1SubstringIndexes.StartIndex,
-				Path:               mostMatchedChallengeeData.path,
-				TFIDFSimilarity:    mostMatchedChallengeeData.tfidfSimilarity,
-				LevenSimilarity:    levenSimilarityResults.Percentage,
-				CombinedSimilarity: combinedSimilarity,
-			}
-		} // else, no data in matchedMap
-	}
-	// free memory
-	tempMemory = nil
+	matchedMap := make(map[string]RepoToRepoMatchedChallengeeData) // map[challengePath]RepoToRepoMatchedChallengeeData
+	for i, j := range assigned {
+		if j < 0 {
+			continue // no data in matchedMap
+		}
+		path, data := paths[i], allDataMap[paths[i]]
+		challengeePath, challengeeData := challengeePaths[j], challengeeAllDataMap[challengeePaths[j]]
+
+		crossLanguage := isCrossLanguagePair(path, challengeePath, options)
+		challengerParsedCodeText := parseCode(data, path, crossLanguage)
+		challengeeParsedCodeText := parseCode(challengeeData, challengeePath, crossLanguage)
+
+		comparison := compareFiles(
+			path, data, challengerParsedCodeText,
+			challengeePath, challengeeData, challengeeParsedCodeText,
+			tfidfSimilarities[filePair{i, j}],
+			baseCode, options,
+		)
+
+		copyDirection := findCopyDirection(
+			submissionHistory, challengeeHistory,
+			path, data,
+			challengeePath, challengeeData,
+			comparison.levenResults,
+		)
 
-	// compute a weighted average score based on NumberOfLinesCopied and CombinedSimilarity
-	totalNumberOfLinesCopied := 0
-	for _, matchedChallengeeData := range matchedMap {
-		totalNumberOfLinesCopied += matchedChallengeeData.NumberOfLinesCopied
+		matchedMap[path] = RepoToRepoMatchedChallengeeData{
+			NumberOfLinesCopied:      comparison.copiedLines,
+			NumberOfCharactersCopied: comparison.copiedLength(),
+			CopiedLinesShare:         comparison.copiedLinesShare,
+			ChallengerPath:           relativePath(repoDir, path),
+			Path:                     relativePath(challengeeDir, challengeePath),
+			TFIDFSimilarity:          tfidfSimilarities[filePair{i, j}],
+			LevenSimilarity:          comparison.levenSimilarity,
+			LevenStartLine1:          comparison.levenResults.Text1StartLine,
+			LevenEndLine1:            comparison.levenResults.Text1EndLine,
+			LevenStartLine2:          comparison.levenResults.Text2StartLine,
+			LevenEndLine2:            comparison.levenResults.Text2EndLine,
+			WinnowingSimilarity:      comparison.winnowingResults.Percentage,
+			WinnowingCoverage1:       comparison.winnowingResults.Text1Coverage,
+			WinnowingCoverage2:       comparison.winnowingResults.Text2Coverage,
+			WinnowingMatchedKGrams:   comparison.winnowingResults.MatchedKGrams,
+			ASTSimilarity:            comparison.similarities[METRIC_AST],
+			ASTMatchedSubtrees:       comparison.matchedSubtrees(),
+			GSTSimilarity:            comparison.gstResults.Percentage,
+			GSTMatchedTiles:          comparison.gstResults.MatchedTiles,
+			CopiedSegments:           comparison.copiedSegmentsResults.Segments,
+			SegmentCoverage1:         comparison.copiedSegmentsResults.Text1Coverage,
+			SegmentCoverage2:         comparison.copiedSegmentsResults.Text2Coverage,
+			CombinedSimilarity:       comparison.combinedSimilarity,
+			Probability:              comparison.probability,
+			BaseCodeShare:            comparison.baseCodeShare,
+			CopyDirection:            copyDirection,
+			CrossLanguage:            crossLanguage,
+		}
 	}
 
 	resultPtr := computeSimilarityScoresWeighted(
-		len(allDataArray), totalNumberOfLinesCopied,
-		matchedMap, challengeeRepoUrl, challengeeRepoName,
+		len(allDataArray), matchedMap, challengeeRepoUrl, challengeeRepoName, options.WeightBy,
+	)
+	// functions are matched across all files, so functions copied into a file of another name are found too
+	resultPtr.MatchedUnits, resultPtr.UnitCoverage = matchFunctionUnits(
+		repoDir, allDataMap, challengeeDir, challengeeAllDataMap, baseCode,
 	)
+	if submissionHistory != nil && challengeeHistory != nil {
+		resultPtr.SharedRootCommit = submissionHistory.SharedRootCommit(challengeeHistory)
+	}
 	return resultPtr
 }
 
 func computePreliminarySimilarityScoresWeighted(
 	totalNumberOfFiles int,
-	totalNumberOfLinesCopied int,
 	challengeeRepoData []*MiniParseCodeWorkflowScanResult,
 	challengeeRepoName string,
+	weightBy string,
 ) *RepoToRepoHighestLikelihoodScores {
+	totalCopied := 0
+	for _, data := range challengeeRepoData {
+		totalCopied += copiedAmount(data.NumberOfLinesCopied, data.NumberOfCharactersCopied, weightBy)
+	}
 
 	weightedCombinedSimilarity := 0.0
 	weightedTFIDFSimilarity := 0.0
 	weightedLevenSimilarity := 0.0
+	weightedWinnowingSimilarity := 0.0
+	weightedASTSimilarity := 0.0
+	weightedGSTSimilarity := 0.0
+	weightedProbability := 0.0
+	weightedBaseCodeShare := 0.0
 	for _, data := range challengeeRepoData {
-		weight := float64(data.NumberOfLinesCopied) / float64(totalNumberOfLinesCopied)
+		weight := copiedWeight(
+			copiedAmount(data.NumberOfLinesCopied, data.NumberOfCharactersCopied, weightBy),
+			totalCopied, len(challengeeRepoData),
+		)
 		weightedCombinedSimilarity += weight * data.CombinedSimilarity
 		weightedTFIDFSimilarity += weight * data.TFIDFSimilarity
 		weightedLevenSimilarity += weight * data.LevenSimilarity
+		weightedWinnowingSimilarity += weight * data.WinnowingSimilarity
+		weightedASTSimilarity += weight * data.ASTSimilarity
+		weightedGSTSimilarity += weight * data.GSTSimilarity
+		weightedProbability += weight * data.Probability
+		weightedBaseCodeShare += weight * data.BaseCodeShare
 	}
 	return &RepoToRepoHighestLikelihoodScores{
-		RepoUrl:                    "https://github.com/" + challengeeRepoName,
-		RepoName:                   challengeeRepoName,
-		TotalNumberOfFiles:         totalNumberOfFiles,
-		SimilarNumberOfFiles:       len(challengeeRepoData),
-		TFIDFSimilarityWeighted:    weightedTFIDFSimilarity,
-		LevenSimilarityWeighted:    weightedLevenSimilarity,
-		CombinedSimilarityWeighted: weightedCombinedSimilarity,
+		RepoUrl:                     repoURL(challengeeRepoName),
+		RepoName:                    challengeeRepoName,
+		TotalNumberOfFiles:          totalNumberOfFiles,
+		SimilarNumberOfFiles:        len(challengeeRepoData),
+		TFIDFSimilarityWeighted:     weightedTFIDFSimilarity,
+		LevenSimilarityWeighted:     weightedLevenSimilarity,
+		WinnowingSimilarityWeighted: weightedWinnowingSimilarity,
+		ASTSimilarityWeighted:       weightedASTSimilarity,
+		GSTSimilarityWeighted:       weightedGSTSimilarity,
+		CombinedSimilarityWeighted:  weightedCombinedSimilarity,
+		ProbabilityWeighted:         weightedProbability,
+		BaseCodeShareWeighted:       weightedBaseCodeShare,
 	}
 }
 
+// computeSimilarityScoresWeighted computes a weighted average score based on the code copied of every file,
+// in characters or lines by weightBy. Cross-language files are scored separately, as their metrics compare
+// language-neutral token streams.
 func computeSimilarityScoresWeighted(
 	totalNumberOfFiles int,
-	totalNumberOfLinesCopied int,
 	matchedMap map[string]RepoToRepoMatchedChallengeeData,
 	challengeeRepoUrl string,
 	challengeeRepoName string,
+	weightBy string,
 ) *RepoToRepoHighestLikelihoodScores {
+	totalCopied := 0
+	crossLanguageCopied := 0
+	similarNumberOfFiles := 0
+	crossLanguageNumberOfFiles := 0
+	copied := make(map[string]int, len(matchedMap))
+	for path, matchedChallengeeData := range matchedMap {
+		copied[path] = copiedAmount(matchedChallengeeData.NumberOfLinesCopied, matchedChallengeeData.NumberOfCharactersCopied, weightBy)
+		if matchedChallengeeData.CrossLanguage {
+			crossLanguageCopied += copied[path]
+			crossLanguageNumberOfFiles++
+		} else {
+			totalCopied += copied[path]
+			similarNumberOfFiles++
+		}
+	}
+
 	weightedCombinedSimilarity := 0.0
 	weightedTFIDFSimilarity := 0.0
 	weightedLevenSimilarity := 0.0
-	for _, matchedChallengeeData := range matchedMap {
-		weight := float64(matchedChallengeeData.NumberOfLinesCopied) / float64(totalNumberOfLinesCopied)
+	weightedWinnowingSimilarity := 0.0
+	weightedASTSimilarity := 0.0
+	weightedGSTSimilarity := 0.0
+	weightedProbability := 0.0
+	weightedBaseCodeShare := 0.0
+	weightedCrossLanguageSimilarity := 0.0
+	matchedFiles := make([]RepoToRepoMatchedChallengeeData, 0, len(matchedMap))
+	for path, matchedChallengeeData := range matchedMap {
+		matchedFiles = append(matchedFiles, matchedChallengeeData)
+		if matchedChallengeeData.CrossLanguage {
+			weight := copiedWeight(copied[path], crossLanguageCopied, crossLanguageNumberOfFiles)
+			weightedCrossLanguageSimilarity += weight * matchedChallengeeData.CombinedSimilarity
+			continue
+		}
+		weight := copiedWeight(copied[path], totalCopied, similarNumberOfFiles)
 		weightedCombinedSimilarity += weight * matchedChallengeeData.CombinedSimilarity
 		weightedTFIDFSimilarity += weight * matchedChallengeeData.TFIDFSimilarity
 		weightedLevenSimilarity += weight * matchedChallengeeData.LevenSimilarity
+		weightedWinnowingSimilarity += weight * matchedChallengeeData.WinnowingSimilarity
+		weightedASTSimilarity += weight * matchedChallengeeData.ASTSimilarity
+		weightedGSTSimilarity += weight * matchedChallengeeData.GSTSimilarity
+		weightedProbability += weight * matchedChallengeeData.Probability
+		weightedBaseCodeShare += weight * matchedChallengeeData.BaseCodeShare
 	}
+	sort.Slice(matchedFiles, func(i, j int) bool {
+		return matchedFiles[i].ChallengerPath < matchedFiles[j].ChallengerPath
+	})
 	return &RepoToRepoHighestLikelihoodScores{
-		RepoUrl:                    challengeeRepoUrl,
-		RepoName:                   challengeeRepoName,
-		TotalNumberOfFiles:         totalNumberOfFiles,
-		SimilarNumberOfFiles:       len(matchedMap),
-		TFIDFSimilarityWeighted:    weightedTFIDFSimilarity,
-		LevenSimilarityWeighted:    weightedLevenSimilarity,
-		CombinedSimilarityWeighted: weightedCombinedSimilarity,
+		RepoUrl:                         challengeeRepoUrl,
+		RepoName:                        challengeeRepoName,
+		TotalNumberOfFiles:              totalNumberOfFiles,
+		SimilarNumberOfFiles:            similarNumberOfFiles,
+		TFIDFSimilarityWeighted:         weightedTFIDFSimilarity,
+		LevenSimilarityWeighted:         weightedLevenSimilarity,
+		WinnowingSimilarityWeighted:     weightedWinnowingSimilarity,
+		ASTSimilarityWeighted:           weightedASTSimilarity,
+		GSTSimilarityWeighted:           weightedGSTSimilarity,
+		CombinedSimilarityWeighted:      weightedCombinedSimilarity,
+		ProbabilityWeighted:             weightedProbability,
+		BaseCodeShareWeighted:           weightedBaseCodeShare,
+		CrossLanguageNumberOfFiles:      crossLanguageNumberOfFiles,
+		CrossLanguageSimilarityWeighted: weightedCrossLanguageSimilarity,
+		MatchedFiles:                    matchedFiles,
+	}
+}
+
+// copiedWeight is the share of a file in the code copied of numberOfFiles files, or an equal share if
+// none of them has copied code, like files matched by their TF-IDF alone
+func copiedWeight(copied int, totalCopied int, numberOfFiles int) float64 {
+	if totalCopied == 0 {
+		return 1 / float64(numberOfFiles)
+	}
+	return float64(copied) / float64(totalCopied)
+}
+
+// copiedAmount is the amount of copied code of a matched file by weightBy
+func copiedAmount(numberOfLinesCopied int, numberOfCharactersCopied int, weightBy string) int {
+	if weightBy == WEIGHT_BY_LINES {
+		return numberOfLinesCopied
+	}
+	return numberOfCharactersCopied
+}
+
+// sortByLikelihood sorts the repos by the probability of the fusion model if there is one,
+// and by combined similarity otherwise, descending order
+func sortByLikelihood(repos []RepoToRepoHighestLikelihoodScores, options Options) {
+	sort.Slice(repos, func(i, j int) bool {
+		if options.fusionModel != nil {
+			return repos[i].ProbabilityWeighted > repos[j].ProbabilityWeighted
+		}
+		return repos[i].CombinedSimilarityWeighted > repos[j].CombinedSimilarityWeighted
+	})
+}
+
+// sortedPaths are the paths of dataMap in order, so that the files are compared in the same order every time
+func sortedPaths(dataMap map[string]string) []string {
+	paths := make([]string, 0, len(dataMap))
+	for path := range dataMap {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func relativePath(root string, path string) string {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
 	}
+	return relPath
 }
 
 func getTopNRepos(possibleRepoMap map[string][]*MiniParseCodeWorkflowScanResult, n int) []string {
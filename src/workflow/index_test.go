@@ -0,0 +1,35 @@
+package workflow
+
+import (
+	"math"
+	"testing"
+)
+
+// files matched by their TF-IDF alone have no copied code to weigh them by
+func TestWeightedScoresWithoutCopiedCode(t *testing.T) {
+	preliminary := computePreliminarySimilarityScoresWeighted(2, []*MiniParseCodeWorkflowScanResult{
+		{RepositoryName: "a/b", TFIDFSimilarity: 0.9},
+		{RepositoryName: "a/b", TFIDFSimilarity: 0.7},
+	}, "a/b", WEIGHT_BY_CHARACTERS)
+	if !isClose(preliminary.TFIDFSimilarityWeighted, 0.8) {
+		t.Errorf("preliminary TFIDFSimilarityWeighted = %f, expected 0.8", preliminary.TFIDFSimilarityWeighted)
+	}
+
+	scores := computeSimilarityScoresWeighted(3, map[string]RepoToRepoMatchedChallengeeData{
+		"main.go":   {ChallengerPath: "main.go", TFIDFSimilarity: 0.9, CombinedSimilarity: 0.6},
+		"util.go":   {ChallengerPath: "util.go", TFIDFSimilarity: 0.5, CombinedSimilarity: 0.4},
+		"helper.py": {ChallengerPath: "helper.py", CombinedSimilarity: 0.3, CrossLanguage: true},
+	}, "https://github.com/a/b", "a/b", WEIGHT_BY_LINES)
+	if !isClose(scores.TFIDFSimilarityWeighted, 0.7) || !isClose(scores.CombinedSimilarityWeighted, 0.5) {
+		t.Errorf("TFIDFSimilarityWeighted = %f, CombinedSimilarityWeighted = %f, expected 0.7 and 0.5",
+			scores.TFIDFSimilarityWeighted, scores.CombinedSimilarityWeighted)
+	}
+	if !isClose(scores.CrossLanguageSimilarityWeighted, 0.3) {
+		t.Errorf("CrossLanguageSimilarityWeighted = %f, expected 0.3", scores.CrossLanguageSimilarityWeighted)
+	}
+}
+
+// isClose is false for NaN
+func isClose(value float64, expected float64) bool {
+	return math.Abs(value-expected) <= 1e-9
+}
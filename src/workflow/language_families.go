@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/base_code"
+	"hercules/src/code_parser"
+	"hercules/src/tfidf"
+	"hercules/src/util"
+	"path/filepath"
+	"strings"
+)
+
+const NEUTRAL_TOKEN_NGRAM_LENGTH = 3
+
+// DEFAULT_LANGUAGE_FAMILIES are the extensions of languages code is commonly ported between
+var DEFAULT_LANGUAGE_FAMILIES = [][]string{
+	{".c", ".h", ".cc", ".cpp", ".cxx", ".hpp", ".hh", ".hxx", ".cs"},
+	{".java", ".kt", ".kts", ".scala", ".groovy", ".cs"},
+	{".py", ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".rb", ".php"},
+}
+
+// ParseLanguageFamily parses a comma separated list of extensions, e.g. "java,kt" or ".c,.cpp"
+func ParseLanguageFamily(extensionsList string) ([]string, error) {
+	var family []string
+	for _, extension := range strings.Split(extensionsList, ",") {
+		extension = strings.TrimSpace(strings.ToLower(extension))
+		if extension == "" {
+			continue
+		}
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		family = append(family, extension)
+	}
+	if len(family) < 2 {
+		return nil, fmt.Errorf("a language family needs at least two extensions, got %q", extensionsList)
+	}
+	return family, nil
+}
+
+// isCrossLanguagePair is true if the files are in different languages of one of the language families
+// of the options, and so are compared by their language-neutral token streams
+func isCrossLanguagePair(path1 string, path2 string, options Options) bool {
+	if util.IsExtensionSame(path1, path2) {
+		return false
+	}
+	extension1 := strings.ToLower(filepath.Ext(path1))
+	extension2 := strings.ToLower(filepath.Ext(path2))
+	for _, family := range options.LanguageFamilies {
+		if util.Contains(family, extension1) && util.Contains(family, extension2) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCode parses a file for a comparison with a file of the same language, or for a cross-language
+// comparison
+func parseCode(text string, path string, crossLanguage bool) *code_parser.ParsedCodeTextObject {
+	if crossLanguage {
+		return code_parser.ParseNeutralCode(text, path)
+	}
+	return code_parser.ParseCode(text, path)
+}
+
+// neutralTFIDFWeights are the TFIDF weights of the n-grams of the language-neutral token streams of
+// the files at paths, without their base code, by path, over the documents of all files. Their cosine
+// is the CLNAT similarity of cross-language pairs, as the characters of two languages differ too much.
+func neutralTFIDFWeights(dataMaps []map[string]string, paths []string, baseCode *base_code.BaseCode) map[string]map[string]float64 {
+	neutralTexts := make(map[string]string)
+	for _, dataMap := range dataMaps {
+		for path, data := range dataMap {
+			neutralTexts[path] = code_parser.ParseNeutralCode(baseCode.StripBaseLines(data), path).ParsedCodeText
+		}
+	}
+	neutralTFIDF := tfidf.NewTokenizerFunc(tfidf.TokenizeTokenNGrams(NEUTRAL_TOKEN_NGRAM_LENGTH))
+	neutralTFIDF.AddDocs(loadAllData(neutralTexts))
+
+	weights := make(map[string]map[string]float64, len(paths))
+	for _, path := range paths {
+		if _, ok := weights[path]; !ok {
+			weights[path] = neutralTFIDF.Cal(neutralTexts[path])
+		}
+	}
+	return weights
+}
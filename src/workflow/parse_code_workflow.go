@@ -2,9 +2,10 @@ package workflow
 
 import (
 	"fmt"
+	"hercules/src/base_code"
 	"hercules/src/code_parser"
 	"hercules/src/git_repo"
-	"hercules/src/similarity_compute"
+	"hercules/src/lexer"
 	"hercules/src/tfidf"
 	"hercules/src/util"
 	"path/filepath"
@@ -15,11 +16,17 @@ import (
 )
 
 type MiniParseCodeWorkflowScanResult struct {
-	RepositoryName      string
-	NumberOfLinesCopied int
-	TFIDFSimilarity     float64
-	LevenSimilarity     float64
-	CombinedSimilarity  float64
+	RepositoryName           string
+	NumberOfLinesCopied      int // non-blank lines of the file copied
+	NumberOfCharactersCopied int
+	TFIDFSimilarity          float64
+	LevenSimilarity          float64
+	WinnowingSimilarity      float64
+	ASTSimilarity            float64
+	GSTSimilarity            float64
+	CombinedSimilarity       float64
+	Probability              float64 // of the fusion model, 0 without one
+	BaseCodeShare            float64 // share of the matched region that is base code
 }
 
 const NUMBER_OF_FILES_TO_QUERY = 10
@@ -29,6 +36,8 @@ func ParseCodeWorkflow(
 	path string,
 	isTempPath bool,
 	codeText string,
+	baseCode *base_code.BaseCode,
+	options Options,
 	keywordsTFIDF *tfidf.TFIDF,
 	keywordsTFIDFMutex *sync.Mutex,
 	charLevelTFIDF *tfidf.TFIDF,
@@ -37,12 +46,8 @@ func ParseCodeWorkflow(
 	possibleRepoMapMutex *sync.Mutex,
 ) (int, error) {
 	fileExt := filepath.Ext(path)
-	parsedCodeText := code_parser.ParseCodeText(codeText)
-	keywordsTFIDFMutex.Lock()
-	codeTextWeights := keywordsTFIDF.Cal(codeText)
-	keywordsTFIDFMutex.Unlock()
-
-	topKeywords := tfidf.GetTopNKeywordsTfIdf(4, codeTextWeights)
+	parsedCodeText := code_parser.ParseCode(codeText, path)
+	topKeywords := searchKeywords(path, codeText, baseCode, keywordsTFIDF, keywordsTFIDFMutex)
 	var extQuery string
 	if fileExt == "" {
 		extQuery = ""
@@ -76,39 +81,15 @@ func ParseCodeWorkflow(
 			challengeeCodeText, err := git_repo.FetchRawFileFromGitHub(item)
 			challengeeCodeText = challengeeCodeText[:util.Min(TEXT_MAX_LENGTH, len(challengeeCodeText))] // to prevent OOM
 			util.Check(err)
-			// if challengeeCodeText is too long, or vice versa, ignore
-			// 2x difference max
-			// since codeText and challengeeCodeText is already capped at TEXT_MAX_LENGTH
-			// it's okay to do this comparison for the sake of no OOM
-			if (len(challengeeCodeText) > len(codeText)*2) || (len(codeText) > len(challengeeCodeText)*2) {
-				return
-			}
-			challengeeCodeText = challengeeCodeText[:util.Min(TEXT_MAX_LENGTH, len(challengeeCodeText))] // to prevent OOM
-
-			parsedCodeTextToCompare := code_parser.ParseCodeText(challengeeCodeText)
-
-			similarityResults := similarity_compute.ComputeLevenSimilarity(
-				parsedCodeText,
-				parsedCodeTextToCompare,
+			result := scanFile(
+				path, codeText, parsedCodeText,
+				item.Repository.FullName, item.Path, challengeeCodeText,
+				baseCode, options, charLevelTFIDF, charLevelTFIDFMutex,
 			)
-
-			charLevelTFIDFMutex.Lock()
-			charLevelTFIDF.AddDocs([]string{challengeeCodeText})
-			w1 := charLevelTFIDF.Cal(parsedCodeText.ParsedCodeText)
-			w2 := charLevelTFIDF.Cal(parsedCodeTextToCompare.ParsedCodeText)
-			charLevelTFIDFMutex.Unlock()
-
-			tfidfSimilarity := similarity.Cosine(w1, w2)
-
-			result := MiniParseCodeWorkflowScanResult{
-				RepositoryName:      item.Repository.FullName,
-				NumberOfLinesCopied: similarityResults.Text1SubstringIndexes.EndIndex - similarityResults.Text1SubstringIndexes.StartIndex,
-				TFIDFSimilarity:     tfidfSimilarity,
-				LevenSimilarity:     similarityResults.Percentage,
-				CombinedSimilarity:  similarityResults.Percentage * tfidfSimilarity,
+			if result == nil {
+				return
 			}
-
-			resultChannel <- &result
+			resultChannel <- result
 		}(item)
 	}
 
@@ -118,18 +99,103 @@ func ParseCodeWorkflow(
 		close(sem)
 	}()
 
-	// collect results as they become available
-	// and increase the count of the repo name
+	return addScanResults(resultChannel, possibleRepoMap, possibleRepoMapMutex), nil
+}
+
+// searchKeywords are the terms of the file with the highest TFIDF, to search for the file with
+func searchKeywords(
+	path string,
+	codeText string,
+	baseCode *base_code.BaseCode,
+	keywordsTFIDF *tfidf.TFIDF,
+	keywordsTFIDFMutex *sync.Mutex,
+) []string {
+	keywordsTFIDFMutex.Lock()
+	codeTextWeights := keywordsTFIDF.Cal(baseCode.StripBaseLines(codeText))
+	keywordsTFIDFMutex.Unlock()
+	// keywords and standard library names of the file's language are in every file of it
+	if language := lexer.LanguageFromPath(path); language != nil {
+		tfidf.RemoveStopWords(codeTextWeights, language.Name)
+	}
+	return tfidf.GetTopNKeywordsTfIdf(4, codeTextWeights)
+}
+
+// scanFile compares a file of the submission with a file found for it in a repo.
+// It returns nil if the sizes of the files are too different.
+func scanFile(
+	path string,
+	codeText string,
+	parsedCodeText *code_parser.ParsedCodeTextObject,
+	challengeeRepoName string,
+	challengeePath string,
+	challengeeCodeText string,
+	baseCode *base_code.BaseCode,
+	options Options,
+	charLevelTFIDF *tfidf.TFIDF,
+	charLevelTFIDFMutex *sync.Mutex,
+) *MiniParseCodeWorkflowScanResult {
+	// if challengeeCodeText is too long, or vice versa, ignore
+	// 2x difference max
+	// since codeText and challengeeCodeText is already capped at TEXT_MAX_LENGTH
+	// it's okay to do this comparison for the sake of no OOM
+	if (len(challengeeCodeText) > len(codeText)*2) || (len(codeText) > len(challengeeCodeText)*2) {
+		return nil
+	}
+	challengeeCodeText = challengeeCodeText[:util.Min(TEXT_MAX_LENGTH, len(challengeeCodeText))] // to prevent OOM
+
+	parsedCodeTextToCompare := code_parser.ParseCode(challengeeCodeText, challengeePath)
+
+	charLevelTFIDFMutex.Lock()
+	charLevelTFIDF.AddDocs([]string{baseCode.StripBaseLines(challengeeCodeText)})
+	w1 := charLevelTFIDF.Cal(baseCode.StripBaseLines(codeText))
+	w2 := charLevelTFIDF.Cal(baseCode.StripBaseLines(challengeeCodeText))
+	charLevelTFIDFMutex.Unlock()
+
+	tfidfSimilarity := similarity.Cosine(w1, w2)
+
+	comparison := compareFiles(
+		path, codeText, parsedCodeText,
+		challengeePath, challengeeCodeText, parsedCodeTextToCompare,
+		tfidfSimilarity,
+		baseCode, options,
+	)
+
+	return &MiniParseCodeWorkflowScanResult{
+		RepositoryName:           challengeeRepoName,
+		NumberOfLinesCopied:      comparison.copiedLines,
+		NumberOfCharactersCopied: comparison.copiedLength(),
+		TFIDFSimilarity:          tfidfSimilarity,
+		LevenSimilarity:          comparison.levenSimilarity,
+		WinnowingSimilarity:      comparison.winnowingResults.Percentage,
+		ASTSimilarity:            comparison.similarities[METRIC_AST],
+		GSTSimilarity:            comparison.gstResults.Percentage,
+		CombinedSimilarity:       comparison.combinedSimilarity,
+		Probability:              comparison.probability,
+		BaseCodeShare:            comparison.baseCodeShare,
+	}
+}
+
+// addScanResults adds the results above any threshold to the repos they were found in, as they become
+// available, and returns their number
+func addScanResults(
+	resultChannel <-chan *MiniParseCodeWorkflowScanResult,
+	possibleRepoMap map[string][]*MiniParseCodeWorkflowScanResult,
+	possibleRepoMapMutex *sync.Mutex,
+) int {
 	count := 0
 	for result := range resultChannel {
 		if result.CombinedSimilarity > COMBINED_SIMILARITY_THRESHOLD ||
 			result.TFIDFSimilarity > TFIDF_SIMILARITY_THRESHOLD ||
-			result.LevenSimilarity > LEVEN_SIMILARITY_THRESHOLD {
+			result.LevenSimilarity > LEVEN_SIMILARITY_THRESHOLD ||
+			result.WinnowingSimilarity > WINNOWING_SIMILARITY_THRESHOLD ||
+			result.ASTSimilarity > AST_SIMILARITY_THRESHOLD ||
+			result.GSTSimilarity > GST_SIMILARITY_THRESHOLD ||
+			result.Probability > PROBABILITY_THRESHOLD {
 			possibleRepoMapMutex.Lock()
 			possibleRepoMap[result.RepositoryName] = append(possibleRepoMap[result.RepositoryName], result)
 			possibleRepoMapMutex.Unlock()
 			count++
 		}
 	}
-	return count, nil
+	return count
 }
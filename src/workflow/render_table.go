@@ -2,19 +2,66 @@ package workflow
 
 import (
 	"fmt"
+	"hercules/src/git_history"
+	"hercules/src/git_repo"
+	"hercules/src/similarity_compute"
+	"hercules/src/syntax_tree"
+	"hercules/src/util"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 )
 
+const MAX_DESCRIBED_TILES = 3
+const MAX_DESCRIBED_SEGMENTS = 5
+const MAX_DESCRIBED_KGRAM_RANGES = 3
+const KGRAM_RUN_GAP_LINES = 3 // winnowing keeps one k-gram of every window, so a copied block's k-grams skip lines
+
 func RenderTable(repoName string, highlyLikelyRepos []RepoToRepoHighestLikelihoodScores) {
 	fmt.Println("-----------------------------------")
 	fmt.Println("Repository to compare: " + repoName)
 	fmt.Printf("Top %d Repositories\n", len(highlyLikelyRepos))
 	fmt.Println("If any of the values are green, then the challenged repo is likely a copy of the repo in question.")
 
+	// the base code column is only shown when a base was given, the probability column with a fusion model,
+	// the unit coverage column after the repo-to-repo evaluation, and the cross-language column when
+	// files in another language were matched
+	showBaseCode := false
+	showProbability := false
+	showUnitCoverage := false
+	showCrossLanguage := false
+	for _, repo := range highlyLikelyRepos {
+		if repo.BaseCodeShareWeighted > 0 {
+			showBaseCode = true
+		}
+		if repo.ProbabilityWeighted > 0 {
+			showProbability = true
+		}
+		if repo.UnitCoverage > 0 {
+			showUnitCoverage = true
+		}
+		if repo.CrossLanguageNumberOfFiles > 0 {
+			showCrossLanguage = true
+		}
+	}
+
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Repo URL", "Number of Files Similar", "TFIDF Weighted", "Argmin Leven Weighted", "Combined Sim Weighted"})
+	header := []string{"Repo URL", "Number of Files Similar", "TFIDF Weighted", "Argmin Leven Weighted", "Winnowing Weighted", "AST Weighted", "GST Weighted", "Combined Sim Weighted"}
+	if showUnitCoverage {
+		header = append(header, "Function Coverage")
+	}
+	if showCrossLanguage {
+		header = append(header, "Cross-Language Weighted")
+	}
+	if showProbability {
+		header = append(header, "Avg File Probability")
+	}
+	if showBaseCode {
+		header = append(header, "Base Code Weighted")
+	}
+	table.SetHeader(header)
 
 	for _, repo := range highlyLikelyRepos {
 		tfidfSimilarityColors := tablewriter.Colors{tablewriter.BgBlackColor}
@@ -27,6 +74,21 @@ func RenderTable(repoName string, highlyLikelyRepos []RepoToRepoHighestLikelihoo
 			levenSimilarityColors = tablewriter.Colors{tablewriter.FgGreenColor}
 		}
 
+		winnowingSimilarityColors := tablewriter.Colors{tablewriter.BgBlackColor}
+		if repo.WinnowingSimilarityWeighted > WINNOWING_SIMILARITY_THRESHOLD {
+			winnowingSimilarityColors = tablewriter.Colors{tablewriter.FgGreenColor}
+		}
+
+		astSimilarityColors := tablewriter.Colors{tablewriter.BgBlackColor}
+		if repo.ASTSimilarityWeighted > AST_SIMILARITY_THRESHOLD {
+			astSimilarityColors = tablewriter.Colors{tablewriter.FgGreenColor}
+		}
+
+		gstSimilarityColors := tablewriter.Colors{tablewriter.BgBlackColor}
+		if repo.GSTSimilarityWeighted > GST_SIMILARITY_THRESHOLD {
+			gstSimilarityColors = tablewriter.Colors{tablewriter.FgGreenColor}
+		}
+
 		combinedSimilarityColors := tablewriter.Colors{tablewriter.BgBlackColor}
 		if repo.CombinedSimilarityWeighted > COMBINED_SIMILARITY_THRESHOLD {
 			combinedSimilarityColors = tablewriter.Colors{tablewriter.FgGreenColor}
@@ -37,11 +99,256 @@ func RenderTable(repoName string, highlyLikelyRepos []RepoToRepoHighestLikelihoo
 			fmt.Sprintf("%d\\%d", repo.SimilarNumberOfFiles, repo.TotalNumberOfFiles),
 			fmt.Sprintf("%.4f", repo.TFIDFSimilarityWeighted),
 			fmt.Sprintf("%.4f", repo.LevenSimilarityWeighted),
+			fmt.Sprintf("%.4f", repo.WinnowingSimilarityWeighted),
+			fmt.Sprintf("%.4f", repo.ASTSimilarityWeighted),
+			fmt.Sprintf("%.4f", repo.GSTSimilarityWeighted),
 			fmt.Sprintf("%.4f", repo.CombinedSimilarityWeighted),
 		}
+		colors := []tablewriter.Colors{{}, {}, tfidfSimilarityColors, levenSimilarityColors, winnowingSimilarityColors, astSimilarityColors, gstSimilarityColors, combinedSimilarityColors}
+		if showUnitCoverage {
+			unitCoverageColors := tablewriter.Colors{tablewriter.BgBlackColor}
+			if repo.UnitCoverage > UNIT_COVERAGE_THRESHOLD {
+				unitCoverageColors = tablewriter.Colors{tablewriter.FgGreenColor}
+			}
+			row = append(row, fmt.Sprintf("%.0f%%", repo.UnitCoverage*100))
+			colors = append(colors, unitCoverageColors)
+		}
+		if showCrossLanguage {
+			crossLanguageColors := tablewriter.Colors{tablewriter.BgBlackColor}
+			if repo.CrossLanguageSimilarityWeighted > COMBINED_SIMILARITY_THRESHOLD {
+				crossLanguageColors = tablewriter.Colors{tablewriter.FgGreenColor}
+			}
+			row = append(row, fmt.Sprintf("%.4f (%d files)", repo.CrossLanguageSimilarityWeighted, repo.CrossLanguageNumberOfFiles))
+			colors = append(colors, crossLanguageColors)
+		}
+		if showProbability {
+			probabilityColors := tablewriter.Colors{tablewriter.BgBlackColor}
+			if repo.ProbabilityWeighted > PROBABILITY_THRESHOLD {
+				probabilityColors = tablewriter.Colors{tablewriter.FgGreenColor}
+			}
+			row = append(row, fmt.Sprintf("%.0f%%", repo.ProbabilityWeighted*100))
+			colors = append(colors, probabilityColors)
+		}
+		if showBaseCode {
+			row = append(row, fmt.Sprintf("%.0f%%", repo.BaseCodeShareWeighted*100))
+			colors = append(colors, tablewriter.Colors{})
+		}
+
+		table.Rich(row, colors)
+	}
+
+	table.Render()
+}
+
+func RenderExcludedCandidatesTable(excludedCandidates []ExcludedCandidate) {
+	if len(excludedCandidates) == 0 {
+		return
+	}
+	fmt.Println("-----------------------------------")
+	fmt.Printf("Excluded %d Candidate Repositories\n", len(excludedCandidates))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Repo URL", "Reason"})
+	for _, excludedCandidate := range excludedCandidates {
+		table.Append([]string{
+			git_repo.RepoURL(excludedCandidate.RepoName),
+			describeExcludedCandidate(excludedCandidate),
+		})
+	}
+	table.Render()
+}
+
+func RenderMatchedFilesTable(highlyLikelyRepos []RepoToRepoHighestLikelihoodScores) {
+	for _, repo := range highlyLikelyRepos {
+		if len(repo.MatchedFiles) == 0 {
+			continue
+		}
+		numberOfLinesCopied := 0
+		for _, matchedFile := range repo.MatchedFiles {
+			numberOfLinesCopied += matchedFile.NumberOfLinesCopied
+		}
+		fmt.Println("-----------------------------------")
+		fmt.Printf("Matched files of %s, %d lines copied\n", repo.RepoUrl, numberOfLinesCopied)
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"File", "Matched File", "Lines Copied", "Winnowing (File/Matched)", "Copied Segments", "AST Matches", "GST Tiles", "Combined Sim", "Probability", "Base Code", "Copy Direction"})
+		for _, matchedFile := range repo.MatchedFiles {
+			copyDirectionColors := tablewriter.Colors{}
+			if matchedFile.CopyDirection == git_history.SOURCE_PREDATES_SUBMISSION {
+				copyDirectionColors = tablewriter.Colors{tablewriter.FgGreenColor}
+			}
+			row := []string{
+				matchedFile.ChallengerPath,
+				describeMatchedPath(matchedFile),
+				describeCopiedLines(matchedFile),
+				describeMatchedKGrams(matchedFile),
+				describeCopiedSegments(matchedFile),
+				describeMatchedSubtrees(matchedFile.ASTMatchedSubtrees),
+				describeMatchedTiles(matchedFile.GSTMatchedTiles),
+				fmt.Sprintf("%.4f", matchedFile.CombinedSimilarity),
+				describeProbability(repo, matchedFile),
+				fmt.Sprintf("%.0f%%", matchedFile.BaseCodeShare*100),
+				matchedFile.CopyDirection,
+			}
+			table.Rich(row, []tablewriter.Colors{{}, {}, {}, {}, {}, {}, {}, {}, {}, {}, copyDirectionColors})
+		}
+		table.Render()
+	}
+}
+
+// describeMatchedPath labels the files of another language, whose metrics compare language-neutral
+// token streams
+func describeMatchedPath(matchedFile RepoToRepoMatchedChallengeeData) string {
+	if matchedFile.CrossLanguage {
+		return matchedFile.Path + " (cross-language)"
+	}
+	return matchedFile.Path
+}
+
+func RenderMatchedUnitsTable(highlyLikelyRepos []RepoToRepoHighestLikelihoodScores) {
+	for _, repo := range highlyLikelyRepos {
+		if len(repo.MatchedUnits) == 0 {
+			continue
+		}
+		fmt.Println("-----------------------------------")
+		fmt.Printf("Matched functions of %s, covering %.0f%% of the functions' lines\n", repo.RepoUrl, repo.UnitCoverage*100)
 
-		table.Rich(row, []tablewriter.Colors{{}, tfidfSimilarityColors, levenSimilarityColors, combinedSimilarityColors})
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"File", "Function", "Lines", "Matched File", "Matched Function", "Matched Lines", "Similarity"})
+		for _, matchedUnit := range repo.MatchedUnits {
+			table.Append([]string{
+				matchedUnit.ChallengerPath,
+				matchedUnit.ChallengerName,
+				fmt.Sprintf("%d-%d", matchedUnit.ChallengerStartLine, matchedUnit.ChallengerEndLine),
+				matchedUnit.Path,
+				matchedUnit.Name,
+				fmt.Sprintf("%d-%d", matchedUnit.StartLine, matchedUnit.EndLine),
+				fmt.Sprintf("%.4f", matchedUnit.Similarity),
+			})
+		}
+		table.Render()
 	}
+}
+
+func RenderHistoryFindingsTable(findings []git_history.HistoryFinding) {
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Println("-----------------------------------")
+	fmt.Printf("%d Findings in the Submission's Commit History\n", len(findings))
 
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Finding", "Commit", "Details"})
+	for _, finding := range findings {
+		table.Append([]string{finding.Kind, finding.Commit[:util.Min(len(finding.Commit), 10)], finding.Description})
+	}
 	table.Render()
 }
+
+// describeMatchedSubtrees counts the matched functions, classes and control flow, e.g. "2 function, 1 class"
+func describeMatchedSubtrees(matchedSubtrees []similarity_compute.MatchedSubtree) string {
+	counts := make(map[string]int)
+	for _, matchedSubtree := range matchedSubtrees {
+		if matchedSubtree.Category != "" {
+			counts[matchedSubtree.Category]++
+		}
+	}
+	var descriptions []string
+	for _, category := range []string{syntax_tree.CATEGORY_FUNCTION, syntax_tree.CATEGORY_CLASS, syntax_tree.CATEGORY_CONTROL_FLOW} {
+		if counts[category] > 0 {
+			descriptions = append(descriptions, fmt.Sprintf("%d %s", counts[category], category))
+		}
+	}
+	if len(descriptions) == 0 {
+		return "-"
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// describeMatchedTiles lists the line ranges of the longest tiles, e.g. "3 tiles: 10-24 ~ 12-26, 40-52 ~ 3-15"
+func describeMatchedTiles(matchedTiles []similarity_compute.MatchedTile) string {
+	if len(matchedTiles) == 0 {
+		return "-"
+	}
+	longestTiles := make([]similarity_compute.MatchedTile, len(matchedTiles))
+	copy(longestTiles, matchedTiles)
+	sort.SliceStable(longestTiles, func(i, j int) bool {
+		return longestTiles[i].Length > longestTiles[j].Length
+	})
+
+	var descriptions []string
+	for _, tile := range longestTiles[:util.Min(len(longestTiles), MAX_DESCRIBED_TILES)] {
+		descriptions = append(descriptions, fmt.Sprintf("%d-%d ~ %d-%d",
+			tile.Text1StartLine, tile.Text1EndLine, tile.Text2StartLine, tile.Text2EndLine))
+	}
+	return fmt.Sprintf("%d tiles: %s", len(matchedTiles), strings.Join(descriptions, ", "))
+}
+
+// describeMatchedKGrams is the winnowing coverage of both files, with the line ranges of the longest runs of
+// matched k-grams, e.g. "0.9658 / 0.9912: 2 ranges: 1-40 ~ 1-38, 52-60 ~ 70-78"
+func describeMatchedKGrams(matchedFile RepoToRepoMatchedChallengeeData) string {
+	description := fmt.Sprintf("%.4f / %.4f", matchedFile.WinnowingCoverage1, matchedFile.WinnowingCoverage2)
+	if len(matchedFile.WinnowingMatchedKGrams) == 0 {
+		return description
+	}
+
+	// the k-grams are in the order of the file, the next k-gram of a run starts at most KGRAM_RUN_GAP_LINES
+	// after the end of the run in both files
+	var ranges []similarity_compute.MatchedKGram
+	for _, kGram := range matchedFile.WinnowingMatchedKGrams {
+		if len(ranges) > 0 {
+			last := &ranges[len(ranges)-1]
+			if kGram.Text1StartLine <= last.Text1EndLine+KGRAM_RUN_GAP_LINES && kGram.Text2StartLine >= last.Text2StartLine &&
+				kGram.Text2StartLine <= last.Text2EndLine+KGRAM_RUN_GAP_LINES {
+				last.Text1EndLine = util.Max(last.Text1EndLine, kGram.Text1EndLine)
+				last.Text2EndLine = util.Max(last.Text2EndLine, kGram.Text2EndLine)
+				continue
+			}
+		}
+		ranges = append(ranges, kGram)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Text1EndLine-ranges[i].Text1StartLine > ranges[j].Text1EndLine-ranges[j].Text1StartLine
+	})
+
+	var descriptions []string
+	for _, kGramRange := range ranges[:util.Min(len(ranges), MAX_DESCRIBED_KGRAM_RANGES)] {
+		descriptions = append(descriptions, fmt.Sprintf("%d-%d ~ %d-%d",
+			kGramRange.Text1StartLine, kGramRange.Text1EndLine, kGramRange.Text2StartLine, kGramRange.Text2EndLine))
+	}
+	return fmt.Sprintf("%s: %d ranges: %s", description, len(ranges), strings.Join(descriptions, ", "))
+}
+
+// describeCopiedSegments lists the copied blocks with their similarity, and how much of both files they cover,
+// e.g. "2 segments (62% / 80%): 11-16 ~ 1-6 (1.00), 1-4 ~ 12-15 (0.95)"
+func describeCopiedSegments(matchedFile RepoToRepoMatchedChallengeeData) string {
+	if len(matchedFile.CopiedSegments) == 0 {
+		return "-"
+	}
+	var descriptions []string
+	for _, segment := range matchedFile.CopiedSegments[:util.Min(len(matchedFile.CopiedSegments), MAX_DESCRIBED_SEGMENTS)] {
+		descriptions = append(descriptions, fmt.Sprintf("%d-%d ~ %d-%d (%.2f)",
+			segment.Text1StartLine, segment.Text1EndLine, segment.Text2StartLine, segment.Text2EndLine, segment.Percentage))
+	}
+	return fmt.Sprintf("%d segments (%.0f%% / %.0f%%): %s", len(matchedFile.CopiedSegments),
+		matchedFile.SegmentCoverage1*100, matchedFile.SegmentCoverage2*100, strings.Join(descriptions, ", "))
+}
+
+// describeCopiedLines is the number of non-blank lines copied and their share of the file, with the lines of
+// the DAL substring in both files if no copied segment is long enough, e.g. "42 (61%): 10-55 ~ 3-48"
+func describeCopiedLines(matchedFile RepoToRepoMatchedChallengeeData) string {
+	description := fmt.Sprintf("%d (%.0f%%)", matchedFile.NumberOfLinesCopied, matchedFile.CopiedLinesShare*100)
+	if len(matchedFile.CopiedSegments) > 0 || matchedFile.LevenStartLine1 == 0 || matchedFile.LevenStartLine2 == 0 {
+		return description
+	}
+	return fmt.Sprintf("%s: %d-%d ~ %d-%d", description,
+		matchedFile.LevenStartLine1, matchedFile.LevenEndLine1, matchedFile.LevenStartLine2, matchedFile.LevenEndLine2)
+}
+
+// describeProbability is the probability of the fusion model that the file was copied, if there is one
+func describeProbability(repo RepoToRepoHighestLikelihoodScores, matchedFile RepoToRepoMatchedChallengeeData) string {
+	if repo.ProbabilityWeighted == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", matchedFile.Probability*100)
+}
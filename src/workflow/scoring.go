@@ -0,0 +1,89 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/similarity_compute"
+	"hercules/src/syntax_tree"
+	"hercules/src/util"
+	"strings"
+)
+
+const (
+	METRIC_DAL       = "dal"
+	METRIC_CLNAT     = "clnat"
+	METRIC_WINNOWING = "winnowing"
+	METRIC_AST       = "ast"
+	METRIC_GST       = "gst"
+)
+
+var ALL_METRICS = []string{METRIC_DAL, METRIC_CLNAT, METRIC_WINNOWING, METRIC_AST, METRIC_GST}
+var DEFAULT_COMBINED_METRICS = []string{METRIC_DAL, METRIC_CLNAT}
+
+// ParseMetrics parses a comma separated list of metrics, e.g. "dal,clnat,winnowing"
+func ParseMetrics(metricsList string) ([]string, error) {
+	var metrics []string
+	for _, metric := range strings.Split(metricsList, ",") {
+		metric = strings.TrimSpace(strings.ToLower(metric))
+		if metric == "" {
+			continue
+		}
+		if !util.Contains(ALL_METRICS, metric) {
+			return nil, fmt.Errorf("unknown metric %q, must be one of %s", metric, strings.Join(ALL_METRICS, ", "))
+		}
+		metrics = append(metrics, metric)
+	}
+	if len(metrics) == 0 {
+		return nil, fmt.Errorf("no metrics given")
+	}
+	return metrics, nil
+}
+
+// combineSimilarities multiplies the similarities of the chosen metrics,
+// which for the default metrics is DAL * CLNAT. Metrics that couldn't be
+// computed for a pair, like AST for an unsupported language, are left out.
+// If none of them was computed, the default metrics are combined instead,
+// and without those the combined similarity is 0, never the empty product.
+func combineSimilarities(similarities map[string]float64, metrics []string) float64 {
+	if len(metrics) == 0 {
+		metrics = DEFAULT_COMBINED_METRICS
+	}
+	if combinedSimilarity, ok := multiplySimilarities(similarities, metrics); ok {
+		return combinedSimilarity
+	}
+	combinedSimilarity, _ := multiplySimilarities(similarities, DEFAULT_COMBINED_METRICS)
+	return combinedSimilarity
+}
+
+// multiplySimilarities is the product of the similarities of the metrics that were computed,
+// and false if none was
+func multiplySimilarities(similarities map[string]float64, metrics []string) (float64, bool) {
+	combinedSimilarity := 1.0
+	computed := false
+	for _, metric := range metrics {
+		if similarity, ok := similarities[metric]; ok {
+			combinedSimilarity *= similarity
+			computed = true
+		}
+	}
+	if !computed {
+		return 0, false
+	}
+	return combinedSimilarity, true
+}
+
+// computeStructuralSimilarity adds the AST similarity to similarities if both files can be parsed
+func computeStructuralSimilarity(
+	similarities map[string]float64,
+	text1 string, path1 string,
+	text2 string, path2 string,
+) *similarity_compute.StructuralResults {
+	if !syntax_tree.IsSupported(path1) || !util.IsExtensionSame(path1, path2) {
+		return nil
+	}
+	structuralResults, err := similarity_compute.ComputeStructuralSimilarity(text1, path1, text2, path2)
+	if err != nil {
+		return nil
+	}
+	similarities[METRIC_AST] = structuralResults.Percentage
+	return structuralResults
+}
@@ -0,0 +1,235 @@
+package workflow
+
+import (
+	"fmt"
+	"hercules/src/base_code"
+	"hercules/src/code_parser"
+	"hercules/src/git_history"
+	"hercules/src/score_fusion"
+	"hercules/src/util"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/wilcosheh/tfidf/similarity"
+)
+
+// TrainFusionModel trains a score fusion model on the labeled pairs of the CSV file at pairsPath,
+// see score_fusion.ReadLabeledPairs, and saves it to outPath. The metrics of the pairs are
+// computed with the options, which should be those the model will be used with. The pairs held out
+// by trainOptions.HoldOutShare aren't trained on, and show how calibrated the model is on new pairs.
+func TrainFusionModel(pairsPath string, outPath string, trainOptions score_fusion.TrainOptions, options Options) error {
+	pairs, err := score_fusion.ReadLabeledPairs(pairsPath)
+	if err != nil {
+		return err
+	}
+	_, features, err := compareFilePairs(pairs, options)
+	if err != nil {
+		return err
+	}
+	examples := make([]score_fusion.Example, len(pairs))
+	for i, pair := range pairs {
+		examples[i] = score_fusion.Example{Features: features[i], Label: pair.Label}
+	}
+	trainingExamples, heldOutExamples := score_fusion.SplitHoldOut(examples, trainOptions.HoldOutShare, trainOptions.Seed)
+
+	model, err := score_fusion.Train(trainingExamples, score_fusion.ALL_FEATURES, trainOptions)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Trained on %d pairs: log loss %.4f, accuracy %.4f\n",
+		len(trainingExamples), model.LogLoss(trainingExamples), model.Accuracy(trainingExamples))
+	RenderFusionModelTable(model)
+	if len(heldOutExamples) > 0 {
+		fmt.Printf("Held out %d pairs: log loss %.4f, accuracy %.4f, Brier score %.4f\n",
+			len(heldOutExamples), model.LogLoss(heldOutExamples), model.Accuracy(heldOutExamples), model.Brier(heldOutExamples))
+		RenderReliabilityTable(model.Reliability(heldOutExamples))
+	}
+	fmt.Printf("Saving the model to %s\n", outPath)
+	return model.Save(outPath)
+}
+
+// compareFilePairs compares the files of every pair and returns the comparisons and the features
+// of the fusion model
+func compareFilePairs(pairs []score_fusion.LabeledPair, options Options) ([]*fileComparison, []map[string]float64, error) {
+	var filePaths []string
+	for _, pair := range pairs {
+		for _, path := range []string{pair.Path1, pair.Path2} {
+			if !util.Contains(filePaths, path) {
+				filePaths = append(filePaths, path)
+			}
+		}
+	}
+	allDataMap, err := util.MultipleFileRead(filePaths, TEXT_MAX_LENGTH)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	baseCode, err := base_code.Load(options.BaseSources)
+	if err != nil {
+		return nil, nil, err
+	}
+	parsedCodeTexts := make(map[string]*code_parser.ParsedCodeTextObject, len(allDataMap))
+	for path, data := range allDataMap {
+		parsedCodeTexts[path] = code_parser.ParseCode(data, path)
+	}
+	tfidfSimilarities, err := pairCLNATSimilarities(pairs, allDataMap, baseCode, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Printf("Computing the metrics of %d pairs of %d files\n", len(pairs), len(allDataMap))
+	comparisons := make([]*fileComparison, len(pairs))
+	features := make([]map[string]float64, len(pairs))
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i, pair := range pairs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, pair score_fusion.LabeledPair) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			text1, text2 := allDataMap[pair.Path1], allDataMap[pair.Path2]
+			tfidfSimilarity := tfidfSimilarities[i]
+			if isCrossLanguagePair(pair.Path1, pair.Path2, options) {
+				comparisons[i] = compareFiles(
+					pair.Path1, text1, parseCode(text1, pair.Path1, true),
+					pair.Path2, text2, parseCode(text2, pair.Path2, true),
+					tfidfSimilarity,
+					baseCode, options,
+				)
+			} else {
+				comparisons[i] = compareFiles(
+					pair.Path1, text1, parsedCodeTexts[pair.Path1],
+					pair.Path2, text2, parsedCodeTexts[pair.Path2],
+					tfidfSimilarity,
+					baseCode, options,
+				)
+			}
+			features[i] = comparisons[i].features(text1, text2)
+		}(i, pair)
+	}
+	wg.Wait()
+	return comparisons, features, nil
+}
+
+// pairCLNATSimilarities are the CLNAT similarities of the pairs as compareRepos computes them, with an
+// IDF of all code files of the repos of the two files, so the model is trained on the CLNAT it sees when
+// scanning. The repo of a file is its git repo, or its directory if it isn't in one.
+func pairCLNATSimilarities(
+	pairs []score_fusion.LabeledPair,
+	allDataMap map[string]string,
+	baseCode *base_code.BaseCode,
+	options Options,
+) ([]float64, error) {
+	repoDataMaps := make(map[string]map[string]string) // by repo directory
+	readRepo := func(repoDir string) (map[string]string, error) {
+		if _, ok := repoDataMaps[repoDir]; !ok {
+			dataMap, err := readCodeFiles(repoDir)
+			if err != nil {
+				return nil, err
+			}
+			repoDataMaps[repoDir] = dataMap
+		}
+		return repoDataMaps[repoDir], nil
+	}
+
+	// the pairs of the same two repos share one IDF
+	pairIndexes := make(map[[2]string][]int)
+	var repoPairs [][2]string
+	for i, pair := range pairs {
+		repoPair := [2]string{enclosingRepoDir(pair.Path1), enclosingRepoDir(pair.Path2)}
+		if _, ok := pairIndexes[repoPair]; !ok {
+			repoPairs = append(repoPairs, repoPair)
+		}
+		pairIndexes[repoPair] = append(pairIndexes[repoPair], i)
+	}
+
+	similarities := make([]float64, len(pairs))
+	for _, repoPair := range repoPairs {
+		repoDataMap1, err := readRepo(repoPair[0])
+		if err != nil {
+			return nil, err
+		}
+		repoDataMap2, err := readRepo(repoPair[1])
+		if err != nil {
+			return nil, err
+		}
+		charLevelTFIDF := newCLNATTFIDF(options)
+		charLevelTFIDF.AddDocs(util.Map(loadAllData(repoDataMap1), baseCode.StripBaseLines))
+		if repoPair[1] != repoPair[0] {
+			charLevelTFIDF.AddDocs(util.Map(loadAllData(repoDataMap2), baseCode.StripBaseLines))
+		}
+
+		var crossLanguagePaths []string
+		for _, i := range pairIndexes[repoPair] {
+			if isCrossLanguagePair(pairs[i].Path1, pairs[i].Path2, options) {
+				crossLanguagePaths = append(crossLanguagePaths, pairs[i].Path1, pairs[i].Path2)
+			}
+		}
+		var neutralWeights map[string]map[string]float64
+		if len(crossLanguagePaths) > 0 {
+			neutralWeights = neutralTFIDFWeights([]map[string]string{repoDataMap1, repoDataMap2, allDataMap}, crossLanguagePaths, baseCode)
+		}
+
+		for _, i := range pairIndexes[repoPair] {
+			path1, path2 := pairs[i].Path1, pairs[i].Path2
+			if isCrossLanguagePair(path1, path2, options) {
+				similarities[i] = similarity.Cosine(neutralWeights[path1], neutralWeights[path2])
+			} else {
+				similarities[i] = similarity.Cosine(
+					charLevelTFIDF.Cal(baseCode.StripBaseLines(allDataMap[path1])),
+					charLevelTFIDF.Cal(baseCode.StripBaseLines(allDataMap[path2])),
+				)
+			}
+		}
+	}
+	return similarities, nil
+}
+
+// enclosingRepoDir is the root of the git repo of the file, or its directory if it isn't in one
+func enclosingRepoDir(path string) string {
+	if history, err := git_history.Open(filepath.Dir(path)); err == nil {
+		return history.Root
+	}
+	return filepath.Dir(path)
+}
+
+// RenderReliabilityTable shows how often the pairs the model gave a probability were plagiarised
+func RenderReliabilityTable(bins []score_fusion.ReliabilityBin) {
+	fmt.Println("-----------------------------------")
+	fmt.Println("Calibration on the held-out pairs, a calibrated model has the plagiarised share near its mean probability")
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Probability", "Pairs", "Mean Probability", "Plagiarised Share"})
+	for _, bin := range bins {
+		table.Append([]string{
+			fmt.Sprintf("%.0f%% - %.0f%%", bin.Low*100, bin.High*100),
+			fmt.Sprintf("%d", bin.Pairs),
+			fmt.Sprintf("%.4f", bin.MeanProbability),
+			fmt.Sprintf("%.4f", bin.PositiveShare),
+		})
+	}
+	table.Render()
+}
+
+func RenderFusionModelTable(model *score_fusion.Model) {
+	fmt.Println("-----------------------------------")
+	fmt.Println("Weights of the standardized features, positive weights raise the probability")
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Feature", "Weight", "Mean", "Standard Deviation"})
+	for i, feature := range model.Features {
+		table.Append([]string{
+			feature,
+			fmt.Sprintf("%.4f", model.Weights[i]),
+			fmt.Sprintf("%.4f", model.Means[i]),
+			fmt.Sprintf("%.4f", model.Scales[i]),
+		})
+	}
+	table.Append([]string{"(bias)", fmt.Sprintf("%.4f", model.Bias), "", ""})
+	table.Render()
+}